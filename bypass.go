@@ -0,0 +1,34 @@
+package pipe
+
+import (
+	"fmt"
+
+	"pipelined.dev/pipe/mutability"
+)
+
+// Bypass returns mutation that enables or disables bypass of the line
+// processor. Processor is the index in the routing. Bypassed processor
+// isn't executed and its input is passed to the output, so it's only
+// possible if processor doesn't change the number of channels and the
+// sample rate. If crossfade is true, the next buffer crossfades between
+// processed and input signals to avoid clicks. Error is returned if
+// there is no processor with provided index or it can't be bypassed.
+func (l *Line) Bypass(processor int, enabled, crossfade bool) (mutability.Mutation, error) {
+	if processor < 0 || processor >= len(l.routing) {
+		return mutability.Mutation{}, fmt.Errorf("error bypassing processor %d: line has %d processors", processor, len(l.routing))
+	}
+	i := l.routing[processor]
+	// source stage has no runner, so input of runner i is stage i.
+	in, out := l.stages[i].Properties, l.stages[i+1].Properties
+	if in.Channels != out.Channels {
+		return mutability.Mutation{}, fmt.Errorf("error bypassing processor %d: channels %d->%d", processor, in.Channels, out.Channels)
+	}
+	if in.SampleRate != out.SampleRate {
+		return mutability.Mutation{}, fmt.Errorf("error bypassing processor %d: sample rate %v->%v", processor, in.SampleRate, out.SampleRate)
+	}
+	b := l.processors[i].Bypass
+	return b.Mutability.Mutate(func() error {
+		b.Set(enabled, crossfade)
+		return nil
+	}), nil
+}
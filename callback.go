@@ -0,0 +1,204 @@
+package pipe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"unsafe"
+
+	"pipelined.dev/signal"
+
+	"pipelined.dev/pipe/internal/runner"
+	"pipelined.dev/pipe/mutability"
+)
+
+// ErrCallbackChannels is returned by CallbackLine when the host buffer
+// has different number of channels than the line output.
+var ErrCallbackChannels = errors.New("callback buffer has different number of channels")
+
+// CallbackLine executes line components inside a host callback, for
+// example the real-time callback of the audio driver. Components are
+// executed synchronously and the processed signal is written into the
+// buffer provided by the host. Host buffers might have any length, line
+// output that doesn't fit is kept for the next callback. Mutations are
+// passed through lock-free queue, so the callback never blocks.
+type CallbackLine struct {
+	channels  int
+	line      runner.Line
+	listeners map[mutability.Mutability]struct{}
+	mutations mutationQueue
+	// pending contains line output that didn't fit into host buffer,
+	// samples in range [pos, end) are not served yet.
+	pending signal.Floating
+	pos     int
+	end     int
+	err     error
+}
+
+// CallbackLine binds components into the line executed with host
+// callback. The output of the last processor is written into the host
+// buffer. Sink is optional, if provided it receives the same signal,
+// which is useful for monitoring. All allocators are executed, if any of
+// them failed, the error will be returned and flush hooks won't be
+// triggered.
+func (r Routing) CallbackLine(bufferSize int, options ...LineOption) (*CallbackLine, error) {
+	var line Line
+	for _, option := range options {
+		option(&line)
+	}
+	if err := r.bind(&line, bufferSize); err != nil {
+		return nil, err
+	}
+	// output buffers of the line might be longer than the input ones.
+	outSize := line.source.OutPool.Length
+	if n := len(line.processors); n > 0 {
+		outSize = line.processors[n-1].OutPool.Length
+	}
+	l := CallbackLine{
+		channels:  line.stages[len(line.stages)-1].Properties.Channels,
+		line:      line.runner(),
+		listeners: make(map[mutability.Mutability]struct{}),
+		pending: signal.Allocator{
+			Channels: line.stages[len(line.stages)-1].Properties.Channels,
+			Length:   outSize,
+			Capacity: outSize,
+		}.Float64(),
+	}
+	l.listeners[l.line.Source.Mutability] = struct{}{}
+	for i := range l.line.Processors {
+		l.listeners[l.line.Processors[i].Mutability] = struct{}{}
+		for _, id := range l.line.Processors[i].Nested {
+			l.listeners[id] = struct{}{}
+		}
+	}
+	l.listeners[l.line.Sink.Mutability] = struct{}{}
+	return &l, nil
+}
+
+// Push new mutations into the line. It's safe to call Push from any
+// goroutine. Mutations will be applied in the beginning of the next
+// callback.
+func (l *CallbackLine) Push(mutations ...mutability.Mutation) {
+	for _, m := range mutations {
+		if _, ok := l.listeners[m.Mutability]; ok {
+			l.mutations.push(m)
+		}
+	}
+}
+
+// Process executes line components and writes the result into provided
+// buffer. It must be called from the host callback and doesn't
+// allocate. If source is done, the rest of the buffer is filled with
+// silence and io.EOF is returned by the next call. If error occurs, the
+// buffer is filled with silence and the error is returned. All
+// subsequent calls fill the buffer with silence and return the same
+// error. Flush hooks aren't called inside the callback, host must call
+// Flush after the line is done.
+func (l *CallbackLine) Process(out signal.Floating) error {
+	if l.err != nil {
+		silence(out, 0)
+		return l.err
+	}
+	if out.Channels() != l.channels {
+		silence(out, 0)
+		return ErrCallbackChannels
+	}
+	n, err := l.process(out)
+	if err != nil && (err != io.EOF || n == 0) {
+		silence(out, 0)
+		l.err = err
+		return err
+	}
+	if err == io.EOF {
+		l.err = err
+	}
+	silence(out, n)
+	return nil
+}
+
+// Flush calls flush hooks of all line components. It must be called
+// outside of the host callback when the line is done.
+func (l *CallbackLine) Flush(ctx context.Context) error {
+	return l.line.Flush(ctx)
+}
+
+// process applies mutations and fills the buffer with pending and
+// pulled samples. Returns the number of samples per channel written.
+func (l *CallbackLine) process(out signal.Floating) (int, error) {
+	var mutationErr error
+	// all popped mutations are applied, the first error is returned.
+	for n := l.mutations.pop(); n != nil; n = n.next {
+		if err := n.Apply(); err != nil && mutationErr == nil {
+			mutationErr = err
+		}
+	}
+	if mutationErr != nil {
+		return 0, fmt.Errorf("error mutating callback line: %w", mutationErr)
+	}
+	var written int
+	for written < out.Length() {
+		if l.pos == l.end {
+			n, err := l.line.Pull(nil, l.pending)
+			if err != nil {
+				return written, err
+			}
+			l.pos, l.end = 0, n
+			continue
+		}
+		n := out.Length() - written
+		if left := l.end - l.pos; left < n {
+			n = left
+		}
+		copyBlock(out, written, l.pending, l.pos, n)
+		l.pos += n
+		written += n
+	}
+	return written, nil
+}
+
+// silence fills the buffer with zeros starting from provided position.
+func silence(s signal.Floating, pos int) {
+	for i := pos * s.Channels(); i < s.Len(); i++ {
+		s.SetSample(i, 0)
+	}
+}
+
+// mutationQueue is a lock-free multiple-producer single-consumer queue
+// of mutations.
+type mutationQueue struct {
+	head unsafe.Pointer // *mutationNode
+}
+
+type mutationNode struct {
+	mutability.Mutation
+	next *mutationNode
+}
+
+// push mutation into the queue.
+func (q *mutationQueue) push(m mutability.Mutation) {
+	n := &mutationNode{Mutation: m}
+	for {
+		head := atomic.LoadPointer(&q.head)
+		n.next = (*mutationNode)(head)
+		if atomic.CompareAndSwapPointer(&q.head, head, unsafe.Pointer(n)) {
+			return
+		}
+	}
+}
+
+// pop all mutations from the queue in the order they were pushed.
+func (q *mutationQueue) pop() *mutationNode {
+	var (
+		n    = (*mutationNode)(atomic.SwapPointer(&q.head, nil))
+		prev *mutationNode
+	)
+	// reverse the list to restore push order.
+	for n != nil {
+		next := n.next
+		n.next = prev
+		prev, n = n, next
+	}
+	return prev
+}
@@ -0,0 +1,313 @@
+// Command pipe runs, inspects and benchmarks pipelines defined in
+// declarative specs. See package spec for the document format.
+//
+// Usage:
+//
+//	pipe run [-fusion] spec.yaml
+//	pipe graph spec.yaml
+//	pipe validate spec.yaml
+//	pipe bench [-n runs] [-d duration] spec.yaml
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	ossignal "os/signal"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"pipelined.dev/signal"
+
+	"pipelined.dev/pipe"
+	"pipelined.dev/pipe/generator"
+	"pipelined.dev/pipe/spec"
+)
+
+const usage = `usage: pipe <command> [flags] spec
+
+commands:
+  run       execute the spec until EOF or interrupt and print stats
+  graph     print the topology in graphviz DOT format
+  validate  check the spec and print the negotiated stages
+  bench     measure the throughput of the spec with generated input
+`
+
+var errUsage = errors.New("invalid usage")
+
+func main() {
+	ctx, cancelFn := ossignal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancelFn()
+	if err := run(ctx, registry, os.Args[1:], os.Stdout); err != nil {
+		if errors.Is(err, errUsage) {
+			fmt.Fprint(os.Stderr, usage)
+			os.Exit(2)
+		}
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// run executes the command provided in arguments. Components of the
+// spec are created by the registry of provided constructor. Only run
+// command writes files, others use dry registry.
+func run(ctx context.Context, registry func(dry bool) *spec.Registry, args []string, w io.Writer) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	flags := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	var cmd func(context.Context, io.Writer, *spec.Spec) error
+	switch args[0] {
+	case "run":
+		fusion := flags.Bool("fusion", false, "execute processors of each line in a single goroutine")
+		cmd = func(ctx context.Context, w io.Writer, s *spec.Spec) error {
+			return runSpec(ctx, w, s, *fusion)
+		}
+	case "graph":
+		cmd = graph
+	case "validate":
+		cmd = validate
+	case "bench":
+		runs := flags.Int("n", 5, "number of runs")
+		duration := flags.Duration("d", 10*time.Second, "duration of generated input if length of the source is unknown")
+		cmd = func(ctx context.Context, w io.Writer, s *spec.Spec) error {
+			return bench(ctx, w, s, *runs, *duration)
+		}
+	default:
+		return errUsage
+	}
+	if err := flags.Parse(args[1:]); err != nil {
+		return errUsage
+	}
+	if flags.NArg() != 1 {
+		return errUsage
+	}
+	s, err := load(registry(args[0] != "run"), flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	return cmd(ctx, w, s)
+}
+
+// load reads the spec file.
+func load(r *spec.Registry, path string) (*spec.Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s, err := r.Load(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return s, nil
+}
+
+// stats collects the timing of line stages.
+type stats struct {
+	mu     sync.Mutex
+	stages map[string]*stageStats
+}
+
+type stageStats struct {
+	calls int
+	total time.Duration
+}
+
+// middleware returns timing middleware that reports into stats. Stages
+// are prefixed with the line index.
+func (s *stats) middleware(line int) pipe.Middleware {
+	return pipe.Timing(func(name string, d time.Duration) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		key := fmt.Sprintf("line %d %s", line, name)
+		st, ok := s.stages[key]
+		if !ok {
+			st = &stageStats{}
+			s.stages[key] = st
+		}
+		st.calls++
+		st.total += d
+	})
+}
+
+// print writes stats sorted by stage name.
+func (s *stats) print(w io.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var names []string
+	for name := range s.stages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		st := s.stages[name]
+		fmt.Fprintf(w, "%-24s calls: %-8d total: %-12v avg: %v\n", name, st.calls, st.total, st.total/time.Duration(st.calls))
+	}
+}
+
+// runSpec executes the spec until it's done or context is canceled.
+// Cancellation is not an error.
+func runSpec(ctx context.Context, w io.Writer, s *spec.Spec, fusion bool) error {
+	st := stats{stages: make(map[string]*stageStats)}
+	var lines []*pipe.Line
+	for i := range s.Routes {
+		l, err := s.Routes[i].Line(s.BufferSize, pipe.Use(st.middleware(i)))
+		if err != nil {
+			return fmt.Errorf("error binding line %d: %w", i, err)
+		}
+		lines = append(lines, l)
+	}
+	options := []pipe.Option{pipe.WithLines(lines...)}
+	if fusion {
+		options = append(options, pipe.WithFusion())
+	}
+	start := time.Now()
+	err := pipe.New(ctx, options...).Wait()
+	if err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+	fmt.Fprintf(w, "elapsed: %v\n", time.Since(start))
+	st.print(w)
+	return nil
+}
+
+// graph prints the topology of the spec.
+func graph(_ context.Context, w io.Writer, s *spec.Spec) error {
+	lines, err := s.Lines()
+	if err != nil {
+		return err
+	}
+	return pipe.DOT(w, lines...)
+}
+
+// validate binds the spec and prints the stages of each line.
+func validate(_ context.Context, w io.Writer, s *spec.Spec) error {
+	lines, err := s.Lines()
+	if err != nil {
+		return err
+	}
+	for i, l := range lines {
+		fmt.Fprintf(w, "line %d:\n", i)
+		for _, st := range l.Stages() {
+			fmt.Fprintf(w, "  %-32s %v Hz, %d ch, %v\n", st.Name, st.Properties.SampleRate, st.Properties.Channels, st.SampleType)
+		}
+	}
+	fmt.Fprintln(w, "ok")
+	return nil
+}
+
+// bench executes the spec multiple times and prints the throughput.
+// Sources are replaced with generators of the same signal properties,
+// so the result doesn't depend on the input. Sources of unknown length
+// are generated for provided duration. Spec is loaded with dry registry,
+// so sinks don't write files. Lines are bound for each run,
+// because components keep the state.
+func bench(ctx context.Context, w io.Writer, s *spec.Spec, runs int, duration time.Duration) error {
+	if runs < 1 || duration <= 0 {
+		return errUsage
+	}
+	props, err := sourceProperties(ctx, s)
+	if err != nil {
+		return err
+	}
+	var (
+		elapsed time.Duration
+		samples int
+		seconds float64
+	)
+	for i := 0; i < runs; i++ {
+		counters := make([]counter, len(s.Routes))
+		var lines []*pipe.Line
+		for j := range s.Routes {
+			route := s.Routes[j]
+			route.Source = benchSource(props[j], duration)
+			l, err := route.Line(s.BufferSize, pipe.Use(counters[j].middleware()))
+			if err != nil {
+				return fmt.Errorf("error binding line %d: %w", j, err)
+			}
+			lines = append(lines, l)
+		}
+		start := time.Now()
+		if err := pipe.New(ctx, pipe.WithLines(lines...)).Wait(); err != nil {
+			return err
+		}
+		elapsed += time.Since(start)
+		for j, l := range lines {
+			stages := l.Stages()
+			sampleRate := stages[len(stages)-1].Properties.SampleRate
+			samples += counters[j].samples
+			seconds += float64(counters[j].samples) / float64(sampleRate)
+		}
+	}
+	avg := elapsed / time.Duration(runs)
+	fmt.Fprintf(w, "runs: %d\n", runs)
+	fmt.Fprintf(w, "avg time: %v\n", avg)
+	fmt.Fprintf(w, "samples/s: %.0f\n", float64(samples)/elapsed.Seconds())
+	fmt.Fprintf(w, "realtime: %.1fx\n", seconds/elapsed.Seconds())
+	return nil
+}
+
+// sourceProperties allocates the sources of the spec to get properties
+// of their signal. Sources are flushed right away to release resources.
+func sourceProperties(ctx context.Context, s *spec.Spec) ([]pipe.SignalProperties, error) {
+	props := make([]pipe.SignalProperties, len(s.Routes))
+	for i := range s.Routes {
+		source, p, err := s.Routes[i].Source(s.BufferSize)
+		if err != nil {
+			return nil, fmt.Errorf("error allocating source of line %d: %w", i, err)
+		}
+		if source.FlushFunc != nil {
+			if err := source.FlushFunc(ctx); err != nil {
+				return nil, fmt.Errorf("error flushing source of line %d: %w", i, err)
+			}
+		}
+		props[i] = p
+	}
+	return props, nil
+}
+
+// benchSource returns white noise generator with provided properties.
+func benchSource(props pipe.SignalProperties, duration time.Duration) pipe.SourceAllocatorFunc {
+	length := props.Length
+	if length == 0 {
+		length = int(duration.Seconds() * float64(props.SampleRate))
+	}
+	return (&generator.Generator{
+		Waveform:   generator.WhiteNoise,
+		Amplitude:  0.5,
+		Channels:   props.Channels,
+		SampleRate: props.SampleRate,
+		Length:     length,
+		Seed:       1,
+	}).Source()
+}
+
+// counter counts samples consumed by the sink.
+type counter struct {
+	samples int
+}
+
+func (c *counter) middleware() pipe.Middleware {
+	return pipe.Middleware{
+		Sink: func(_ string, s pipe.Sink) pipe.Sink {
+			if fn := s.SinkFunc; fn != nil {
+				s.SinkFunc = func(in signal.Floating) error {
+					c.samples += in.Length()
+					return fn(in)
+				}
+			}
+			if fn := s.SignedSinkFunc; fn != nil {
+				s.SignedSinkFunc = func(in signal.Signed) error {
+					c.samples += in.Length()
+					return fn(in)
+				}
+			}
+			return s
+		},
+	}
+}
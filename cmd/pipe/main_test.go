@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testSpec = `
+bufferSize: 512
+lines:
+  - source:
+      type: mock
+      params:
+        limit: 48000
+        channels: 2
+        sampleRate: 48000
+        value: 0.5
+    processors:
+      - type: mock
+    sink:
+      type: mock
+  - source:
+      type: generator
+      params:
+        waveform: sine
+        frequency: 1000
+        length: 44100
+    processors:
+      - type: gain
+        params:
+          decibels: -6
+      - type: dcblock
+      - type: resample
+        params:
+          sampleRate: 48000
+    sink:
+      type: mock
+`
+
+func TestRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spec.yaml")
+	if err := os.WriteFile(path, []byte(testSpec), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	testCommand := func(args []string, expected ...string) func(*testing.T) {
+		return func(t *testing.T) {
+			t.Helper()
+			var out bytes.Buffer
+			err := run(context.Background(), testRegistry, append(args, path), &out)
+			assertEqual(t, "error", err, nil)
+			for _, s := range expected {
+				assertEqual(t, s, strings.Contains(out.String(), s), true)
+			}
+		}
+	}
+	t.Run("run", testCommand([]string{"run"}, "elapsed", "line 0 source", "line 0 processor 0", "line 0 sink", "line 1 source"))
+	t.Run("run fusion", testCommand([]string{"run", "-fusion"}, "line 0 processor 0"))
+	t.Run("graph", testCommand([]string{"graph"}, "digraph pipe", "44100 Hz, 2 ch", "48000 Hz, 2 ch"))
+	t.Run("validate", testCommand([]string{"validate"}, "processor 0", "ok"))
+	t.Run("bench", testCommand([]string{"bench", "-n", "2"}, "runs: 2", "realtime"))
+}
+
+func TestBench(t *testing.T) {
+	// empty mock source, bench generates the input instead.
+	path := filepath.Join(t.TempDir(), "spec.yaml")
+	spec := "bufferSize: 512\nlines:\n  - source: {type: mock, params: {limit: 0}}\n    sink: {type: mock}\n"
+	if err := os.WriteFile(path, []byte(spec), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	err := run(context.Background(), testRegistry, []string{"bench", "-n", "1", "-d", "100ms", path}, &out)
+	assertEqual(t, "error", err, nil)
+	assertEqual(t, "generated", strings.Contains(out.String(), "samples/s: 0\n"), false)
+}
+
+func TestWav(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.wav")
+	testSpec := func(name, source, sink string) string {
+		spec := fmt.Sprintf("bufferSize: 512\nlines:\n  - source: %s\n    sink: %s\n", source, sink)
+		specPath := filepath.Join(dir, name)
+		if err := os.WriteFile(specPath, []byte(spec), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return specPath
+	}
+	write := testSpec("write.yaml",
+		"{type: generator, params: {length: 1000}}",
+		fmt.Sprintf("{type: wav, params: {path: %q, encoding: s24le}}", path),
+	)
+	read := testSpec("read.yaml",
+		fmt.Sprintf("{type: wav, params: {path: %q}}", path),
+		"{type: mock}",
+	)
+	err := run(context.Background(), testRegistry, []string{"run", write}, &bytes.Buffer{})
+	assertEqual(t, "write error", err, nil)
+	var out bytes.Buffer
+	err = run(context.Background(), testRegistry, []string{"validate", read}, &out)
+	assertEqual(t, "read error", err, nil)
+	assertEqual(t, "properties", strings.Contains(out.String(), "44100 Hz, 2 ch"), true)
+}
+
+func TestDryRun(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.wav")
+	content := []byte("existing file")
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	spec := fmt.Sprintf("bufferSize: 512\nlines:\n  - source: {type: generator, params: {length: 1000}}\n    sink: {type: wav, params: {path: %q}}\n", path)
+	specPath := filepath.Join(dir, "spec.yaml")
+	if err := os.WriteFile(specPath, []byte(spec), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{{"validate"}, {"graph"}, {"bench", "-n", "1"}} {
+		err := run(context.Background(), testRegistry, append(args, specPath), &bytes.Buffer{})
+		assertEqual(t, args[0]+" error", err, nil)
+		data, err := os.ReadFile(path)
+		assertEqual(t, "read error", err, nil)
+		assertEqual(t, args[0]+" output file", data, content)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	invalid := filepath.Join(t.TempDir(), "spec.yaml")
+	if err := os.WriteFile(invalid, []byte("bufferSize: 0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	testError := func(args []string, usage bool) func(*testing.T) {
+		return func(t *testing.T) {
+			t.Helper()
+			err := run(context.Background(), testRegistry, args, &bytes.Buffer{})
+			assertEqual(t, "error", err != nil, true)
+			assertEqual(t, "usage", errors.Is(err, errUsage), usage)
+		}
+	}
+	t.Run("no command", testError(nil, true))
+	t.Run("unknown command", testError([]string{"play", invalid}, true))
+	t.Run("no spec", testError([]string{"run"}, true))
+	t.Run("invalid spec", testError([]string{"validate", invalid}, false))
+	t.Run("missing spec", testError([]string{"graph", "missing.yaml"}, false))
+
+	// mocks are only available in tests.
+	path := filepath.Join(t.TempDir(), "spec.yaml")
+	if err := os.WriteFile(path, []byte(testSpec), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	err := run(context.Background(), registry, []string{"validate", path}, &bytes.Buffer{})
+	assertEqual(t, "mock error", err != nil, true)
+}
+
+func assertEqual(t *testing.T, name string, result, expected interface{}) {
+	t.Helper()
+	if !reflect.DeepEqual(expected, result) {
+		t.Fatalf("%v\nresult: \t%T\t%+v \nexpected: \t%T\t%+v", name, result, result, expected, expected)
+	}
+}
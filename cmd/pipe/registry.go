@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+
+	"pipelined.dev/signal"
+
+	"pipelined.dev/pipe"
+	"pipelined.dev/pipe/generator"
+	"pipelined.dev/pipe/pcm"
+	"pipelined.dev/pipe/processors"
+	"pipelined.dev/pipe/spec"
+	"pipelined.dev/pipe/wav"
+)
+
+// registry returns the registry of components available in specs. If
+// dry is true, files are only inspected: wav sources read the header and
+// close the file right away, wav sinks encode the signal and discard it.
+func registry(dry bool) *spec.Registry {
+	r := spec.NewRegistry()
+	r.Source("generator", generatorSource)
+	r.Processor("gain", gainProcessor)
+	r.Processor("pan", panProcessor)
+	r.Processor("polarity", polarityProcessor)
+	r.Processor("dcblock", dcBlockProcessor)
+	r.Processor("resample", resampleProcessor)
+	r.Source("wav", wavSource(dry))
+	r.Sink("wav", wavSink(dry))
+	return r
+}
+
+// generatorSource produces generated signal.
+func generatorSource(p *spec.Params) (pipe.SourceAllocatorFunc, error) {
+	var g generator.Generator
+	name, err := p.String("waveform", "sine")
+	if err != nil {
+		return nil, err
+	}
+	if g.Waveform, err = generator.ParseWaveform(name); err != nil {
+		return nil, err
+	}
+	if g.Frequency, err = p.Float("frequency", 440); err != nil {
+		return nil, err
+	}
+	if g.EndFrequency, err = p.Float("endFrequency", 0); err != nil {
+		return nil, err
+	}
+	if g.Amplitude, err = p.Float("amplitude", 1); err != nil {
+		return nil, err
+	}
+	if g.Channels, err = p.Int("channels", 2); err != nil {
+		return nil, err
+	}
+	sampleRate, err := p.Float("sampleRate", 44100)
+	if err != nil {
+		return nil, err
+	}
+	g.SampleRate = signal.Frequency(sampleRate)
+	if g.Length, err = p.Int("length", 0); err != nil {
+		return nil, err
+	}
+	seed, err := p.Int("seed", 0)
+	if err != nil {
+		return nil, err
+	}
+	g.Seed = int64(seed)
+	return g.Source(), nil
+}
+
+// gainProcessor changes the level of the signal.
+func gainProcessor(p *spec.Params) (pipe.ProcessorAllocatorFunc, error) {
+	var (
+		g   processors.Gain
+		err error
+	)
+	if g.Decibels, err = p.Float("decibels", 0); err != nil {
+		return nil, err
+	}
+	return g.Processor(), nil
+}
+
+// panProcessor places mono signal into stereo field.
+func panProcessor(p *spec.Params) (pipe.ProcessorAllocatorFunc, error) {
+	var (
+		pan processors.Pan
+		err error
+	)
+	if pan.Position, err = p.Float("position", 0); err != nil {
+		return nil, err
+	}
+	return pan.Processor(), nil
+}
+
+// polarityProcessor inverts the polarity of the signal.
+func polarityProcessor(p *spec.Params) (pipe.ProcessorAllocatorFunc, error) {
+	var (
+		polarity processors.Polarity
+		err      error
+	)
+	if polarity.Invert, err = p.Bool("invert", true); err != nil {
+		return nil, err
+	}
+	return polarity.Processor(), nil
+}
+
+// dcBlockProcessor removes DC offset.
+func dcBlockProcessor(p *spec.Params) (pipe.ProcessorAllocatorFunc, error) {
+	var (
+		dc  processors.DCBlock
+		err error
+	)
+	if dc.Cutoff, err = p.Float("cutoff", 0); err != nil {
+		return nil, err
+	}
+	return dc.Processor(), nil
+}
+
+// resampleProcessor converts the sample rate.
+func resampleProcessor(p *spec.Params) (pipe.ProcessorAllocatorFunc, error) {
+	var r processors.Resampler
+	sampleRate, err := p.Float("sampleRate", 0)
+	if err != nil {
+		return nil, err
+	}
+	r.SampleRate = signal.Frequency(sampleRate)
+	name, err := p.String("quality", "sinc")
+	if err != nil {
+		return nil, err
+	}
+	if r.Quality, err = processors.ParseQuality(name); err != nil {
+		return nil, err
+	}
+	return r.Processor(), nil
+}
+
+// wavSource reads wav file. File is closed when the source is flushed.
+// Dry source only reads the header and returns no signal.
+func wavSource(dry bool) spec.SourceFactory {
+	return func(p *spec.Params) (pipe.SourceAllocatorFunc, error) {
+		path, err := p.String("path", "")
+		if err != nil {
+			return nil, err
+		}
+		if path == "" {
+			return nil, errors.New("path is required")
+		}
+		return func(bufferSize int) (pipe.Source, pipe.SignalProperties, error) {
+			f, err := os.Open(path)
+			if err != nil {
+				return pipe.Source{}, pipe.SignalProperties{}, err
+			}
+			source, props, err := (&wav.Source{Reader: f}).Source()(bufferSize)
+			if err != nil {
+				f.Close()
+				return pipe.Source{}, pipe.SignalProperties{}, err
+			}
+			if dry {
+				if err := f.Close(); err != nil {
+					return pipe.Source{}, pipe.SignalProperties{}, err
+				}
+				return pipe.Source{
+					SourceFunc: func(signal.Floating) (int, error) {
+						return 0, io.EOF
+					},
+				}, props, nil
+			}
+			source.FlushFunc = func(context.Context) error {
+				return f.Close()
+			}
+			return source, props, nil
+		}, nil
+	}
+}
+
+// wavSink writes wav file. File is closed when the sink is flushed. Dry
+// sink doesn't create the file.
+func wavSink(dry bool) spec.SinkFactory {
+	return func(p *spec.Params) (pipe.SinkAllocatorFunc, error) {
+		path, err := p.String("path", "")
+		if err != nil {
+			return nil, err
+		}
+		if path == "" {
+			return nil, errors.New("path is required")
+		}
+		name, err := p.String("encoding", pcm.S16LE.String())
+		if err != nil {
+			return nil, err
+		}
+		encoding, err := pcm.ParseEncoding(name)
+		if err != nil {
+			return nil, err
+		}
+		return func(bufferSize int, props pipe.SignalProperties) (pipe.Sink, error) {
+			if dry {
+				return (&wav.Sink{Writer: discard{}, Encoding: encoding}).Sink()(bufferSize, props)
+			}
+			f, err := os.Create(path)
+			if err != nil {
+				return pipe.Sink{}, err
+			}
+			sink, err := (&wav.Sink{Writer: f, Encoding: encoding}).Sink()(bufferSize, props)
+			if err != nil {
+				f.Close()
+				return pipe.Sink{}, err
+			}
+			flush := sink.FlushFunc
+			sink.FlushFunc = func(ctx context.Context) error {
+				if err := flush(ctx); err != nil {
+					f.Close()
+					return err
+				}
+				return f.Close()
+			}
+			return sink, nil
+		}, nil
+	}
+}
+
+// discard is the writer of dry sinks, all writes and seeks succeed.
+type discard struct{}
+
+func (discard) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (discard) Seek(int64, int) (int64, error) {
+	return 0, nil
+}
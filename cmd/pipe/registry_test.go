@@ -0,0 +1,51 @@
+package main
+
+import (
+	"pipelined.dev/signal"
+
+	"pipelined.dev/pipe"
+	"pipelined.dev/pipe/mock"
+	"pipelined.dev/pipe/spec"
+)
+
+// testRegistry returns the registry extended with mock components.
+func testRegistry(dry bool) *spec.Registry {
+	r := registry(dry)
+	r.Source("mock", mockSource)
+	r.Processor("mock", mockProcessor)
+	r.Sink("mock", mockSink)
+	return r
+}
+
+// mockSource produces constant signal of provided length.
+func mockSource(p *spec.Params) (pipe.SourceAllocatorFunc, error) {
+	var (
+		m   mock.Source
+		err error
+	)
+	if m.Limit, err = p.Int("limit", 0); err != nil {
+		return nil, err
+	}
+	if m.Channels, err = p.Int("channels", 2); err != nil {
+		return nil, err
+	}
+	sampleRate, err := p.Float("sampleRate", 44100)
+	if err != nil {
+		return nil, err
+	}
+	m.SampleRate = signal.Frequency(sampleRate)
+	if m.Value, err = p.Float("value", 0); err != nil {
+		return nil, err
+	}
+	return m.Source(), nil
+}
+
+// mockProcessor passes signal through.
+func mockProcessor(*spec.Params) (pipe.ProcessorAllocatorFunc, error) {
+	return (&mock.Processor{}).Processor(), nil
+}
+
+// mockSink discards signal.
+func mockSink(*spec.Params) (pipe.SinkAllocatorFunc, error) {
+	return (&mock.Sink{Discard: true}).Sink(), nil
+}
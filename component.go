@@ -0,0 +1,133 @@
+package pipe
+
+import (
+	"context"
+	"fmt"
+
+	"pipelined.dev/signal"
+
+	"pipelined.dev/pipe/mutability"
+)
+
+type (
+	// SourceComponent is a source implemented as a type. S is the type
+	// of buffers, it must be either signal.Floating or signal.Signed.
+	// Use SourceAllocator to route it.
+	SourceComponent[S signal.Signal] interface {
+		Source(out S) (int, error)
+		Flush(context.Context) error
+		Mutability() mutability.Mutability
+	}
+
+	// ProcessorComponent is a processor implemented as a type. S is the
+	// type of buffers, it must be either signal.Floating or
+	// signal.Signed. Use ProcessorAllocator to route it.
+	ProcessorComponent[S signal.Signal] interface {
+		Process(in, out S) error
+		Flush(context.Context) error
+		Mutability() mutability.Mutability
+	}
+
+	// SinkComponent is a sink implemented as a type. S is the type of
+	// buffers, it must be either signal.Floating or signal.Signed. Use
+	// SinkAllocator to route it.
+	SinkComponent[S signal.Signal] interface {
+		Sink(in S) error
+		Flush(context.Context) error
+		Mutability() mutability.Mutability
+	}
+)
+
+// SourceAllocator returns allocator for source component. The type of
+// buffers must be provided explicitly:
+//
+//	pipe.SourceAllocator[signal.Floating](newSource)
+func SourceAllocator[S signal.Signal, C SourceComponent[S]](fn func(bufferSize int) (C, SignalProperties, error)) SourceAllocatorFunc {
+	return func(bufferSize int) (Source, SignalProperties, error) {
+		c, props, err := fn(bufferSize)
+		if err != nil {
+			return Source{}, SignalProperties{}, err
+		}
+		source := Source{
+			Mutability: c.Mutability(),
+			FlushFunc:  c.Flush,
+		}
+		switch any((*S)(nil)).(type) {
+		case *signal.Floating:
+			source.SourceFunc = func(out signal.Floating) (int, error) {
+				return c.Source(any(out).(S))
+			}
+		case *signal.Signed:
+			source.SignedSourceFunc = func(out signal.Signed) (int, error) {
+				return c.Source(any(out).(S))
+			}
+		default:
+			return Source{}, SignalProperties{}, bufferTypeError[S]()
+		}
+		return source, props, nil
+	}
+}
+
+// ProcessorAllocator returns allocator for processor component. The
+// type of buffers must be provided explicitly:
+//
+//	pipe.ProcessorAllocator[signal.Floating](newProcessor)
+func ProcessorAllocator[S signal.Signal, C ProcessorComponent[S]](fn func(bufferSize int, props SignalProperties) (C, SignalProperties, error)) ProcessorAllocatorFunc {
+	return func(bufferSize int, props SignalProperties) (Processor, SignalProperties, error) {
+		c, props, err := fn(bufferSize, props)
+		if err != nil {
+			return Processor{}, SignalProperties{}, err
+		}
+		processor := Processor{
+			Mutability: c.Mutability(),
+			FlushFunc:  c.Flush,
+		}
+		switch any((*S)(nil)).(type) {
+		case *signal.Floating:
+			processor.ProcessFunc = func(in, out signal.Floating) error {
+				return c.Process(any(in).(S), any(out).(S))
+			}
+		case *signal.Signed:
+			processor.SignedProcessFunc = func(in, out signal.Signed) error {
+				return c.Process(any(in).(S), any(out).(S))
+			}
+		default:
+			return Processor{}, SignalProperties{}, bufferTypeError[S]()
+		}
+		return processor, props, nil
+	}
+}
+
+// SinkAllocator returns allocator for sink component. The type of
+// buffers must be provided explicitly:
+//
+//	pipe.SinkAllocator[signal.Floating](newSink)
+func SinkAllocator[S signal.Signal, C SinkComponent[S]](fn func(bufferSize int, props SignalProperties) (C, error)) SinkAllocatorFunc {
+	return func(bufferSize int, props SignalProperties) (Sink, error) {
+		c, err := fn(bufferSize, props)
+		if err != nil {
+			return Sink{}, err
+		}
+		sink := Sink{
+			Mutability: c.Mutability(),
+			FlushFunc:  c.Flush,
+		}
+		switch any((*S)(nil)).(type) {
+		case *signal.Floating:
+			sink.SinkFunc = func(in signal.Floating) error {
+				return c.Sink(any(in).(S))
+			}
+		case *signal.Signed:
+			sink.SignedSinkFunc = func(in signal.Signed) error {
+				return c.Sink(any(in).(S))
+			}
+		default:
+			return Sink{}, bufferTypeError[S]()
+		}
+		return sink, nil
+	}
+}
+
+func bufferTypeError[S signal.Signal]() error {
+	return fmt.Errorf("unsupported buffer type %T", (*S)(nil))
+}
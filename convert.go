@@ -0,0 +1,181 @@
+package pipe
+
+import (
+	"errors"
+	"fmt"
+
+	"pipelined.dev/signal"
+
+	"pipelined.dev/pipe/internal/resample"
+	"pipelined.dev/pipe/internal/runner"
+)
+
+// ChannelMatrix defines how channels are mixed when the number of
+// channels is converted. Each row is an output channel and each column
+// is a gain of an input channel. All rows must have the same length,
+// otherwise binding of the line fails.
+type ChannelMatrix [][]float64
+
+// conversion holds settings of automatic format conversion.
+type conversion struct {
+	matrices []ChannelMatrix
+}
+
+// unsupported returns accepted properties if conversion is enabled for
+// the line and error is UnsupportedError.
+func (l *Line) unsupported(err error) (Accepts, bool) {
+	if err == nil || l.conversion == nil {
+		return Accepts{}, false
+	}
+	var unsupported *UnsupportedError
+	if !errors.As(err, &unsupported) {
+		return Accepts{}, false
+	}
+	return unsupported.Accepts, true
+}
+
+// convert appends converters that transform the signal into accepted
+// one. Channels are downmixed before and upmixed after the sample rate
+// conversion to reduce the amount of processed samples. Buffer size and
+// signal properties of converted signal are returned.
+func (l *Line) convert(bufferSize int, props SignalProperties, accepts Accepts) (int, SignalProperties) {
+	target := props
+	if !accepts.acceptsSampleRate(props.SampleRate) {
+		target.SampleRate = accepts.SampleRates[0]
+	}
+	if !accepts.acceptsChannels(props.Channels) {
+		target.Channels = accepts.Channels[0]
+	}
+
+	if target.Channels < props.Channels {
+		bufferSize, props = l.mixChannels(bufferSize, props, target.Channels)
+	}
+	if target.SampleRate != props.SampleRate {
+		bufferSize, props = l.resample(bufferSize, props, target.SampleRate)
+	}
+	if target.Channels > props.Channels {
+		bufferSize, props = l.mixChannels(bufferSize, props, target.Channels)
+	}
+	return bufferSize, props
+}
+
+// validate checks that all rows of each matrix have the same number of
+// input channels.
+func (c *conversion) validate() error {
+	if c == nil {
+		return nil
+	}
+	for i, m := range c.matrices {
+		if len(m) == 0 {
+			return fmt.Errorf("channel matrix %d is empty", i)
+		}
+		for o := range m {
+			if len(m[o]) == 0 || len(m[o]) != len(m[0]) {
+				return fmt.Errorf("channel matrix %d: row %d has %d channels, expected %d", i, o, len(m[o]), len(m[0]))
+			}
+		}
+	}
+	return nil
+}
+
+// matrix returns the channel matrix for provided number of channels. If
+// there is no such matrix in conversion settings, the default is used:
+// mono is duplicated into all channels, all channels are averaged into
+// mono, otherwise each output channel averages input channels with the
+// same index modulo the number of output channels.
+func (c *conversion) matrix(in, out int) ChannelMatrix {
+	for _, m := range c.matrices {
+		if len(m) == out && len(m[0]) == in {
+			return m
+		}
+	}
+	m := make(ChannelMatrix, out)
+	for o := range m {
+		m[o] = make([]float64, in)
+	}
+	if in < out {
+		for o := range m {
+			m[o][o%in] = 1
+		}
+		return m
+	}
+	for o := range m {
+		var n float64
+		for i := o; i < in; i += out {
+			n++
+		}
+		for i := o; i < in; i += out {
+			m[o][i] = 1 / n
+		}
+	}
+	return m
+}
+
+// mixChannels appends channel mixer converter.
+func (l *Line) mixChannels(bufferSize int, props SignalProperties, channels int) (int, SignalProperties) {
+	m := l.conversion.matrix(props.Channels, channels)
+	sampleType := l.stages[len(l.stages)-1].SampleType.floating()
+	l.convertSamples(bufferSize, props, sampleType)
+	l.processors = append(l.processors, runner.Processor{
+		InPool:  signal.GetPoolAllocator(props.Channels, bufferSize, bufferSize),
+		OutPool: signal.GetPoolAllocator(channels, bufferSize, bufferSize),
+		InType:  sampleType.runner(),
+		OutType: sampleType.runner(),
+		Fn: runner.FloatingProcessor(func(in, out signal.Floating) error {
+			for i := 0; i < in.Length(); i++ {
+				for o := range m {
+					var v float64
+					for c, gain := range m[o] {
+						v += gain * in.Sample(in.BufferIndex(c, i))
+					}
+					out.SetSample(out.BufferIndex(o, i), v)
+				}
+			}
+			return nil
+		}),
+	})
+	props = props.forward(SignalProperties{
+		SampleRate: props.SampleRate,
+		Channels:   channels,
+	})
+	l.stages = append(l.stages, Stage{
+		Name:       fmt.Sprintf("channel mixer %d->%d", len(m[0]), channels),
+		BufferSize: bufferSize,
+		Properties: props,
+		SampleType: sampleType,
+	})
+	return bufferSize, props
+}
+
+// resample appends linear sample rate converter. The length of output
+// buffers varies, so the buffer size is increased to fit the longest
+// one.
+func (l *Line) resample(bufferSize int, props SignalProperties, sampleRate signal.Frequency) (int, SignalProperties) {
+	r := resample.Linear(props.Channels, props.SampleRate, sampleRate)
+	outSize := r.BufferSize(bufferSize)
+	sampleType := l.stages[len(l.stages)-1].SampleType.floating()
+	l.convertSamples(bufferSize, props, sampleType)
+	l.processors = append(l.processors, runner.Processor{
+		InPool:  signal.GetPoolAllocator(props.Channels, bufferSize, bufferSize),
+		OutPool: signal.GetPoolAllocator(props.Channels, outSize, outSize),
+		InType:  sampleType.runner(),
+		OutType: sampleType.runner(),
+		Fn: runner.FloatingProcessor(func(in, out signal.Floating) error {
+			r.Process(in, out)
+			return nil
+		}),
+		Length: r.Length,
+	})
+	name := fmt.Sprintf("resampler %v->%v Hz", props.SampleRate, sampleRate)
+	props = props.forward(SignalProperties{
+		SampleRate: sampleRate,
+		Channels:   props.Channels,
+	})
+	l.stages = append(l.stages, Stage{
+		Name:       name,
+		BufferSize: bufferSize,
+		Properties: props,
+		SampleType: sampleType,
+	})
+	return outSize, props
+}
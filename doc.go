@@ -45,12 +45,12 @@ Routing and binding
 To run the pipeline, one first need to build it. It starts with a routing:
 
     route := pipe.Routing{
-        Source: &wav.Source{Reader: reader},
+        Source: (&wav.Source{Reader: reader}).Source(),
         Processors: pipe.Processors(
             vst2.Open(vstPath1),
             vst2.Open(vstPath2),
         ),
-        Sink: &wav.Sink{Writer: writer},
+        Sink: (&wav.Sink{Writer: writer}).Sink(),
     }
 
 Routing defines the order in which DSP components form the pipeline. Once
@@ -60,7 +60,17 @@ a line:
     line, err := route.Line(bufferSize)
 
 Line executes all allocators provided in routing and binds components
-together.
+together. Signal properties are validated between components: each of
+them must have non-zero number of channels and known sample rate.
+Processors and sinks can declare accepted properties with Accepts, then
+line returns UnsupportedError if properties can't be satisfied.
+
+Buffers have float64 samples by default. Samples line option switches
+the line to float32 or int32 buffers. Components provide functions for
+floating-point buffers, fixed-point ones or both, sample converters are
+inserted between components with different sample types:
+
+    line, err := route.Line(bufferSize, pipe.Samples(pipe.Int32))
 
 Execution
 
@@ -75,5 +85,11 @@ that pipe should be created:
 
 Pipe will asynchronously run all DSP components until either source or
 context is done.
+
+Line can also be executed synchronously on the caller's goroutine. Each
+call to Next pulls exactly one buffer through all components:
+
+    r := pipe.NewRenderer(context.Background(), line)
+    err := r.Next()
 */
 package pipe
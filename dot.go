@@ -0,0 +1,31 @@
+package pipe
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DOT writes the topology of provided lines in graphviz DOT format. Each
+// line is rendered as a cluster of its stages, edges are labeled with
+// signal properties.
+func DOT(w io.Writer, lines ...*Line) error {
+	var b strings.Builder
+	b.WriteString("digraph pipe {\n")
+	b.WriteString("\trankdir=LR;\n")
+	for i, l := range lines {
+		fmt.Fprintf(&b, "\tsubgraph cluster_%d {\n", i)
+		fmt.Fprintf(&b, "\t\tlabel=\"line %d\";\n", i)
+		for j, s := range l.stages {
+			fmt.Fprintf(&b, "\t\tl%ds%d [label=%q];\n", i, j, s.Name)
+		}
+		for j := 1; j < len(l.stages); j++ {
+			p := l.stages[j-1].Properties
+			fmt.Fprintf(&b, "\t\tl%ds%d -> l%ds%d [label=\"%v Hz, %d ch\"];\n", i, j-1, i, j, p.SampleRate, p.Channels)
+		}
+		b.WriteString("\t}\n")
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,241 @@
+// Package generator provides signal generator sources for testing and
+// calibration.
+package generator
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+
+	"pipelined.dev/signal"
+
+	"pipelined.dev/pipe"
+	"pipelined.dev/pipe/internal/allocation"
+	"pipelined.dev/pipe/mutability"
+)
+
+// Waveform is the shape of the generated signal.
+type Waveform int
+
+// Supported waveforms.
+const (
+	Silence Waveform = iota
+	Sine
+	Square
+	Saw
+	Triangle
+	WhiteNoise
+	PinkNoise
+	Impulse
+	Sweep
+)
+
+var waveforms = [...]string{
+	Silence:    "silence",
+	Sine:       "sine",
+	Square:     "square",
+	Saw:        "saw",
+	Triangle:   "triangle",
+	WhiteNoise: "white",
+	PinkNoise:  "pink",
+	Impulse:    "impulse",
+	Sweep:      "sweep",
+}
+
+// String returns the name of the waveform.
+func (w Waveform) String() string {
+	if w < 0 || int(w) >= len(waveforms) {
+		return fmt.Sprintf("waveform %d", int(w))
+	}
+	return waveforms[w]
+}
+
+// ParseWaveform returns the waveform with provided name.
+func ParseWaveform(name string) (Waveform, error) {
+	for w, n := range waveforms {
+		if n == name {
+			return Waveform(w), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown waveform %q", name)
+}
+
+// periodic returns true if waveform requires frequency.
+func (w Waveform) periodic() bool {
+	switch w {
+	case Sine, Square, Saw, Triangle, Sweep:
+		return true
+	}
+	return false
+}
+
+// Generator is a source of generated signal. The same value is written
+// into all channels. Frequency is the frequency of periodic waveforms in
+// Hz, for impulse it's the rate of impulses and zero means a single
+// impulse. Sweep is exponential, it goes from Frequency to EndFrequency
+// over the Length of the signal. Length is the number of samples per
+// channel, zero means infinite signal. Noise is generated with provided
+// Seed, pink noise has approximately unit amplitude. Each allocated
+// source copies the parameters and has its own mutability. Frequency and
+// amplitude can be mutated while the pipe runs, mutations are applied to
+// the last allocated source.
+type Generator struct {
+	Waveform     Waveform
+	Frequency    float64
+	EndFrequency float64
+	Amplitude    float64
+	Channels     int
+	SampleRate   signal.Frequency
+	Length       int
+	Seed         int64
+
+	last allocation.Last[oscillator]
+}
+
+// SetFrequency returns mutation that changes the frequency of the last
+// allocated source. If source isn't allocated yet, the mutation is a
+// no-op.
+func (g *Generator) SetFrequency(frequency float64) mutability.Mutation {
+	return g.last.Mutate(func(o *oscillator) error {
+		if o.waveform.periodic() && frequency <= 0 {
+			return fmt.Errorf("invalid frequency %v", frequency)
+		}
+		o.frequency = frequency
+		return nil
+	})
+}
+
+// SetAmplitude returns mutation that changes the amplitude of the last
+// allocated source. If source isn't allocated yet, the mutation is a
+// no-op.
+func (g *Generator) SetAmplitude(amplitude float64) mutability.Mutation {
+	return g.last.Mutate(func(o *oscillator) error {
+		o.amplitude = amplitude
+		return nil
+	})
+}
+
+// Source returns allocator of the generator source. Each allocated
+// source starts the signal from the beginning.
+func (g *Generator) Source() pipe.SourceAllocatorFunc {
+	return func(bufferSize int) (pipe.Source, pipe.SignalProperties, error) {
+		if g.Waveform.periodic() && g.Frequency <= 0 {
+			return pipe.Source{}, pipe.SignalProperties{}, fmt.Errorf("%v: invalid frequency %v", g.Waveform, g.Frequency)
+		}
+		if g.Waveform == Sweep && (g.Length <= 0 || g.EndFrequency <= 0) {
+			return pipe.Source{}, pipe.SignalProperties{}, fmt.Errorf("sweep requires length and end frequency")
+		}
+		o := &oscillator{
+			waveform:     g.Waveform,
+			frequency:    g.Frequency,
+			endFrequency: g.EndFrequency,
+			amplitude:    g.Amplitude,
+			sampleRate:   g.SampleRate,
+			length:       g.Length,
+			rand:         rand.New(rand.NewSource(g.Seed)),
+		}
+		return pipe.Source{
+			Mutability: g.last.Set(o),
+			SourceFunc: o.source,
+		}, pipe.SignalProperties{
+			SampleRate: g.SampleRate,
+			Channels:   g.Channels,
+			Length:     g.Length,
+		}, nil
+	}
+}
+
+// oscillator holds the parameters and the state of the generated
+// signal.
+type oscillator struct {
+	waveform     Waveform
+	frequency    float64
+	endFrequency float64
+	amplitude    float64
+	sampleRate   signal.Frequency
+	length       int
+
+	rand  *rand.Rand
+	phase float64
+	// pink noise filter state.
+	b0, b1, b2 float64
+	read       int
+}
+
+func (o *oscillator) source(out signal.Floating) (int, error) {
+	n := out.Length()
+	if o.length > 0 {
+		if o.read == o.length {
+			return 0, io.EOF
+		}
+		if left := o.length - o.read; left < n {
+			n = left
+		}
+	}
+	channels := out.Channels()
+	for i := 0; i < n; i++ {
+		v := o.amplitude * o.next()
+		for c := 0; c < channels; c++ {
+			out.SetSample(out.BufferIndex(c, i), v)
+		}
+		o.read++
+	}
+	return n, nil
+}
+
+// next returns the next sample of the waveform with unit amplitude.
+func (o *oscillator) next() float64 {
+	switch o.waveform {
+	case Sine:
+		return math.Sin(2 * math.Pi * o.advance(o.frequency))
+	case Square:
+		if o.advance(o.frequency) < 0.5 {
+			return 1
+		}
+		return -1
+	case Saw:
+		return 2*o.advance(o.frequency) - 1
+	case Triangle:
+		return 1 - 4*math.Abs(o.advance(o.frequency)-0.5)
+	case WhiteNoise:
+		return o.white()
+	case PinkNoise:
+		// Paul Kellet's economy filter.
+		white := o.white()
+		o.b0 = 0.99765*o.b0 + white*0.0990460
+		o.b1 = 0.96300*o.b1 + white*0.2965164
+		o.b2 = 0.57000*o.b2 + white*1.0526913
+		return (o.b0 + o.b1 + o.b2 + white*0.1848) * 0.25
+	case Impulse:
+		if o.read == 0 {
+			return 1
+		}
+		if o.frequency <= 0 {
+			return 0
+		}
+		prev := o.phase
+		if o.advance(o.frequency); o.phase < prev {
+			return 1
+		}
+		return 0
+	case Sweep:
+		t := float64(o.read) / float64(o.length)
+		return math.Sin(2 * math.Pi * o.advance(o.frequency*math.Pow(o.endFrequency/o.frequency, t)))
+	}
+	return 0
+}
+
+// advance returns the current phase and moves it forward for provided
+// frequency. Phase is in range [0, 1).
+func (o *oscillator) advance(frequency float64) float64 {
+	phase := o.phase
+	o.phase += frequency / float64(o.sampleRate)
+	o.phase -= math.Floor(o.phase)
+	return phase
+}
+
+// white returns uniformly distributed value in range [-1, 1).
+func (o *oscillator) white() float64 {
+	return 2*o.rand.Float64() - 1
+}
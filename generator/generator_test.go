@@ -0,0 +1,201 @@
+package generator_test
+
+import (
+	"context"
+	"io"
+	"math"
+	"reflect"
+	"testing"
+
+	"pipelined.dev/signal"
+
+	"pipelined.dev/pipe"
+	"pipelined.dev/pipe/generator"
+	"pipelined.dev/pipe/mock"
+)
+
+const bufferSize = 512
+
+// render returns the first channel of generated signal.
+func render(t *testing.T, g *generator.Generator) []float64 {
+	t.Helper()
+	sink := &mock.Sink{}
+	line, err := pipe.Routing{
+		Source: g.Source(),
+		Sink:   sink.Sink(),
+	}.Line(bufferSize)
+	assertEqual(t, "error", err, nil)
+	err = pipe.NewRenderer(context.Background(), line).Render(1000)
+	assertEqual(t, "error", err, io.EOF)
+
+	values := make([]float64, sink.Values.Length())
+	for i := range values {
+		values[i] = sink.Values.Sample(sink.Values.BufferIndex(0, i))
+		assertEqual(t, "channels", sink.Values.Sample(sink.Values.BufferIndex(g.Channels-1, i)), values[i])
+	}
+	return values
+}
+
+func TestWaveforms(t *testing.T) {
+	testWaveform := func(waveform generator.Waveform, check func(*testing.T, []float64)) func(*testing.T) {
+		return func(t *testing.T) {
+			t.Helper()
+			values := render(t, &generator.Generator{
+				Waveform:     waveform,
+				Frequency:    1000,
+				EndFrequency: 2000,
+				Amplitude:    0.5,
+				Channels:     2,
+				SampleRate:   8000,
+				Length:       1000,
+				Seed:         1,
+			})
+			assertEqual(t, "length", len(values), 1000)
+			for _, v := range values {
+				// pink noise isn't strictly bounded.
+				if waveform != generator.PinkNoise && math.Abs(v) > 0.5 {
+					t.Fatalf("value %v exceeds amplitude", v)
+				}
+			}
+			check(t, values)
+		}
+	}
+	t.Run("silence", testWaveform(generator.Silence, func(t *testing.T, values []float64) {
+		assertEqual(t, "value", values[10], 0.0)
+	}))
+	t.Run("sine", testWaveform(generator.Sine, func(t *testing.T, values []float64) {
+		assertEqual(t, "start", values[0], 0.0)
+		assertEqual(t, "quarter", math.Round(values[2]*1e9)/1e9, 0.5)
+		assertEqual(t, "period", math.Round((values[8]-values[0])*1e9), 0.0)
+	}))
+	t.Run("square", testWaveform(generator.Square, func(t *testing.T, values []float64) {
+		assertEqual(t, "values", values[:8], []float64{0.5, 0.5, 0.5, 0.5, -0.5, -0.5, -0.5, -0.5})
+	}))
+	t.Run("saw", testWaveform(generator.Saw, func(t *testing.T, values []float64) {
+		assertEqual(t, "values", values[:4], []float64{-0.5, -0.375, -0.25, -0.125})
+	}))
+	t.Run("triangle", testWaveform(generator.Triangle, func(t *testing.T, values []float64) {
+		assertEqual(t, "values", values[:5], []float64{-0.5, -0.25, 0, 0.25, 0.5})
+	}))
+	t.Run("impulse", testWaveform(generator.Impulse, func(t *testing.T, values []float64) {
+		assertEqual(t, "values", values[:9], []float64{0.5, 0, 0, 0, 0, 0, 0, 0, 0.5})
+	}))
+	t.Run("sweep", testWaveform(generator.Sweep, func(t *testing.T, values []float64) {
+		assertEqual(t, "start", values[0], 0.0)
+	}))
+	t.Run("white noise", testWaveform(generator.WhiteNoise, func(t *testing.T, values []float64) {
+		again := render(t, &generator.Generator{
+			Waveform:   generator.WhiteNoise,
+			Amplitude:  0.5,
+			Channels:   1,
+			SampleRate: 8000,
+			Length:     1000,
+			Seed:       1,
+		})
+		assertEqual(t, "seed", again, values)
+	}))
+	t.Run("pink noise", testWaveform(generator.PinkNoise, func(t *testing.T, values []float64) {
+		assertEqual(t, "not silent", values[100] != 0, true)
+	}))
+}
+
+func TestGeneratorErrors(t *testing.T) {
+	testError := func(g *generator.Generator) func(*testing.T) {
+		return func(t *testing.T) {
+			t.Helper()
+			_, err := pipe.Routing{
+				Source: g.Source(),
+				Sink:   (&mock.Sink{}).Sink(),
+			}.Line(bufferSize)
+			assertEqual(t, "error", err != nil, true)
+		}
+	}
+	t.Run("frequency", testError(&generator.Generator{
+		Waveform:   generator.Sine,
+		Channels:   1,
+		SampleRate: 8000,
+	}))
+	t.Run("sweep length", testError(&generator.Generator{
+		Waveform:     generator.Sweep,
+		Frequency:    100,
+		EndFrequency: 1000,
+		Channels:     1,
+		SampleRate:   8000,
+	}))
+}
+
+func TestGeneratorMutations(t *testing.T) {
+	g := &generator.Generator{
+		Waveform:   generator.Square,
+		Frequency:  1000,
+		Amplitude:  1,
+		Channels:   1,
+		SampleRate: 8000,
+	}
+	sink := &mock.Sink{}
+	line, err := pipe.Routing{
+		Source: g.Source(),
+		Sink:   sink.Sink(),
+	}.Line(8)
+	assertEqual(t, "error", err, nil)
+	r := pipe.NewRenderer(context.Background(), line)
+	assertEqual(t, "error", r.Next(), nil)
+
+	r.Push(g.SetAmplitude(0.5), g.SetFrequency(2000))
+	assertEqual(t, "error", r.Next(), nil)
+	values := make([]float64, 8)
+	signal.ReadFloat64(sink.Values.Slice(8, 16), values)
+	assertEqual(t, "values", values, []float64{0.5, 0.5, -0.5, -0.5, 0.5, 0.5, -0.5, -0.5})
+	r.Push(g.SetFrequency(0))
+	assertEqual(t, "error", r.Next() != nil, true)
+}
+
+func TestGeneratorAllocations(t *testing.T) {
+	g := &generator.Generator{
+		Waveform:   generator.Square,
+		Frequency:  1000,
+		Amplitude:  1,
+		Channels:   1,
+		SampleRate: 8000,
+	}
+	// nothing is allocated yet.
+	assertEqual(t, "no-op", g.SetFrequency(2000).Apply(), nil)
+	assertEqual(t, "frequency", g.Frequency, 1000.0)
+
+	first, _, err := g.Source()(8)
+	assertEqual(t, "error", err, nil)
+	second, _, err := g.Source()(8)
+	assertEqual(t, "error", err, nil)
+	assertEqual(t, "mutability", first.Mutability != second.Mutability, true)
+
+	// mutation is applied to the last source only.
+	m := g.SetAmplitude(0.5)
+	assertEqual(t, "target", m.Mutability, second.Mutability)
+	assertEqual(t, "error", m.Apply(), nil)
+	assertEqual(t, "amplitude", g.Amplitude, 1.0)
+	read := func(fn func(signal.Floating) (int, error)) float64 {
+		out := signal.Allocator{Channels: 1, Length: 8, Capacity: 8}.Float64()
+		_, err := fn(out)
+		assertEqual(t, "error", err, nil)
+		return out.Sample(0)
+	}
+	assertEqual(t, "first", read(first.SourceFunc), 1.0)
+	assertEqual(t, "second", read(second.SourceFunc), 0.5)
+}
+
+func TestParseWaveform(t *testing.T) {
+	for _, w := range []generator.Waveform{generator.Silence, generator.Sine, generator.PinkNoise, generator.Sweep} {
+		parsed, err := generator.ParseWaveform(w.String())
+		assertEqual(t, "error", err, nil)
+		assertEqual(t, "waveform", parsed, w)
+	}
+	_, err := generator.ParseWaveform("chirp")
+	assertEqual(t, "error", err != nil, true)
+}
+
+func assertEqual(t *testing.T, name string, result, expected interface{}) {
+	t.Helper()
+	if !reflect.DeepEqual(expected, result) {
+		t.Fatalf("%v\nresult: \t%T\t%+v \nexpected: \t%T\t%+v", name, result, result, expected, expected)
+	}
+}
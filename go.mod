@@ -1,5 +1,8 @@
 module pipelined.dev/pipe
 
-require pipelined.dev/signal v0.8.1-0.20200923112724-a0f620a428a7
+require (
+	gopkg.in/yaml.v3 v3.0.1
+	pipelined.dev/signal v0.8.1-0.20200923112724-a0f620a428a7
+)
 
-go 1.13
+go 1.18
@@ -0,0 +1,44 @@
+// Package allocation tracks the state of allocated components, so their
+// mutations can be applied to it.
+package allocation
+
+import (
+	"sync"
+
+	"pipelined.dev/pipe/mutability"
+)
+
+// Last holds the state and the mutability of the last allocation of the
+// component. Each allocation has its own state and mutability, so
+// allocations used in different lines don't share anything. It's safe to
+// use from multiple goroutines.
+type Last[S any] struct {
+	mu         sync.Mutex
+	mutability mutability.Mutability
+	state      *S
+}
+
+// Set makes provided state the last allocation and returns its new
+// mutability.
+func (l *Last[S]) Set(state *S) mutability.Mutability {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.mutability = mutability.Mutable()
+	l.state = state
+	return l.mutability
+}
+
+// Mutate returns mutation that calls fn with the state of the last
+// allocation. If component isn't allocated yet, zero mutation is
+// returned, which is a no-op.
+func (l *Last[S]) Mutate(fn func(*S) error) mutability.Mutation {
+	l.mu.Lock()
+	id, state := l.mutability, l.state
+	l.mu.Unlock()
+	if state == nil {
+		return mutability.Mutation{}
+	}
+	return id.Mutate(func() error {
+		return fn(state)
+	})
+}
@@ -0,0 +1,37 @@
+package allocation_test
+
+import (
+	"reflect"
+	"testing"
+
+	"pipelined.dev/pipe/internal/allocation"
+	"pipelined.dev/pipe/mutability"
+)
+
+func TestLast(t *testing.T) {
+	var last allocation.Last[int]
+	set := func(v *int) error {
+		*v = 1
+		return nil
+	}
+	m := last.Mutate(set)
+	assertEqual(t, "no-op", m, mutability.Mutation{})
+	assertEqual(t, "no-op error", m.Apply(), nil)
+
+	var first, second int
+	firstID := last.Set(&first)
+	secondID := last.Set(&second)
+	assertEqual(t, "mutability", firstID != secondID, true)
+	m = last.Mutate(set)
+	assertEqual(t, "target", m.Mutability, secondID)
+	assertEqual(t, "error", m.Apply(), nil)
+	assertEqual(t, "first", first, 0)
+	assertEqual(t, "second", second, 1)
+}
+
+func assertEqual(t *testing.T, name string, result, expected interface{}) {
+	t.Helper()
+	if !reflect.DeepEqual(expected, result) {
+		t.Fatalf("%v\nresult: \t%T\t%+v \nexpected: \t%T\t%+v", name, result, result, expected, expected)
+	}
+}
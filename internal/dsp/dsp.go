@@ -0,0 +1,29 @@
+// Package dsp provides signal processing functions shared by filters
+// and analyzers.
+package dsp
+
+import "math"
+
+// Sinc returns the normalized sinc function value.
+func Sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// Cosine returns the value of generalized cosine window with provided
+// coefficients at the phase in range [0, 1].
+func Cosine(a0, a1, a2, phase float64) float64 {
+	x := 2 * math.Pi * phase
+	return a0 - a1*math.Cos(x) + a2*math.Cos(2*x)
+}
+
+// Blackman returns the Blackman window value for x in range [-1, 1], it's
+// zero outside of the range.
+func Blackman(x float64) float64 {
+	if math.Abs(x) >= 1 {
+		return 0
+	}
+	return Cosine(0.42, 0.5, 0.08, (x+1)/2)
+}
@@ -0,0 +1,35 @@
+package dsp_test
+
+import (
+	"math"
+	"testing"
+
+	"pipelined.dev/pipe/internal/dsp"
+)
+
+func TestFunctions(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		fn       func(float64) float64
+		x        float64
+		expected float64
+	}{
+		{"sinc zero", dsp.Sinc, 0, 1},
+		{"sinc crossing", dsp.Sinc, 2, 0},
+		{"sinc half", dsp.Sinc, 0.5, 2 / math.Pi},
+		{"blackman center", dsp.Blackman, 0, 1},
+		{"blackman edge", dsp.Blackman, 1, 0},
+		{"blackman outside", dsp.Blackman, -1.5, 0},
+		{"blackman half", dsp.Blackman, 0.5, 0.34},
+	} {
+		assertNear(t, test.name, test.fn(test.x), test.expected, 1e-12)
+	}
+	assertNear(t, "hann", dsp.Cosine(0.5, 0.5, 0, 0.5), 1, 1e-12)
+}
+
+func assertNear(t *testing.T, name string, result, expected, delta float64) {
+	t.Helper()
+	if math.Abs(result-expected) > delta {
+		t.Fatalf("%v\nresult: \t%v\nexpected: \t%v", name, result, expected)
+	}
+}
@@ -0,0 +1,157 @@
+// Package resample provides sample rate conversion of floating-point
+// signal with linear and windowed-sinc interpolation.
+package resample
+
+import (
+	"math"
+
+	"pipelined.dev/signal"
+
+	"pipelined.dev/pipe/internal/dsp"
+)
+
+// zeroCrossings is the number of sinc zero crossings on each side of the
+// windowed-sinc kernel.
+const zeroCrossings = 16
+
+// Resampler converts sample rate of the signal. Each output sample is
+// the weighted sum of input samples around its position, weights are
+// provided by the interpolation kernel. The length of output buffers
+// varies, use Length to get it before each Process call.
+type Resampler struct {
+	// step is the distance between output samples in input samples.
+	step float64
+	// pos is the position of the next output sample relative to the
+	// beginning of the next input buffer. Negative positions refer to
+	// the history.
+	pos float64
+	// half is the number of kernel taps on each side of the position.
+	half    int
+	kernel  func(x float64) float64
+	latency int
+	// history contains the last input samples of each channel.
+	history [][]float64
+	weights []float64
+}
+
+// Linear returns resampler with linear interpolation. It doesn't delay
+// the signal.
+func Linear(channels int, from, to signal.Frequency) *Resampler {
+	return newResampler(channels, from, to, 1, func(x float64) float64 {
+		return 1 - math.Abs(x)
+	})
+}
+
+// Sinc returns resampler with Blackman-windowed sinc interpolation. When
+// sample rate is decreased, the cutoff of the low-pass filter is lowered
+// to the output Nyquist frequency. The signal is delayed by Latency
+// output samples, so output is produced as soon as input is received.
+func Sinc(channels int, from, to signal.Frequency) *Resampler {
+	cutoff := math.Min(1, float64(to)/float64(from))
+	half := int(math.Ceil(zeroCrossings / cutoff))
+	return newResampler(channels, from, to, half, func(x float64) float64 {
+		return cutoff * dsp.Sinc(cutoff*x) * dsp.Blackman(x/float64(half))
+	})
+}
+
+func newResampler(channels int, from, to signal.Frequency, half int, kernel func(float64) float64) *Resampler {
+	r := Resampler{
+		step:    float64(from) / float64(to),
+		half:    half,
+		kernel:  kernel,
+		history: make([][]float64, channels),
+		weights: make([]float64, 2*half),
+	}
+	if half > 1 {
+		// output is delayed by integer number of output samples, so
+		// kernel never needs input that isn't received yet.
+		r.latency = int(math.Ceil(float64(half) / r.step))
+		r.pos = -float64(r.latency) * r.step
+	}
+	size := int(math.Ceil(-r.pos)) + 2*half
+	for c := range r.history {
+		r.history[c] = make([]float64, size)
+	}
+	return &r
+}
+
+// Latency returns the delay of the signal in output samples.
+func (r *Resampler) Latency() int {
+	return r.latency
+}
+
+// Tail returns the number of input samples per channel needed after the
+// end of the signal to interpolate its last samples.
+func (r *Resampler) Tail() int {
+	return r.half
+}
+
+// BufferSize returns the maximum length of output buffers for provided
+// input buffer size.
+func (r *Resampler) BufferSize(in int) int {
+	return int(float64(in)/r.step) + 2
+}
+
+// Length returns the number of output samples for provided input length.
+// Output sample requires all input samples with non-zero weights.
+func (r *Resampler) Length(in int) int {
+	last := float64(in - r.half)
+	if r.pos > last {
+		return 0
+	}
+	return int(math.Floor((last-r.pos)/r.step)) + 1
+}
+
+// Process interpolates output samples and keeps the end of input in the
+// history for the next call.
+func (r *Resampler) Process(in, out signal.Floating) {
+	channels := in.Channels()
+	for i := 0; i < out.Length(); i++ {
+		pos := r.pos + float64(i)*r.step
+		first := int(math.Floor(pos)) - r.half + 1
+		var sum float64
+		for j := range r.weights {
+			r.weights[j] = r.kernel(float64(first+j) - pos)
+			sum += r.weights[j]
+		}
+		for c := 0; c < channels; c++ {
+			var v float64
+			for j, w := range r.weights {
+				if w != 0 {
+					v += w * r.sample(in, c, first+j)
+				}
+			}
+			// normalization keeps the gain of the kernel at unity.
+			out.SetSample(out.BufferIndex(c, i), v/sum)
+		}
+	}
+	r.pos += float64(out.Length())*r.step - float64(in.Length())
+	r.keep(in)
+}
+
+// sample returns input sample, negative indices refer to the history.
+// Samples after the end of input are only needed with zero weights.
+func (r *Resampler) sample(in signal.Floating, channel, idx int) float64 {
+	if idx < 0 {
+		h := r.history[channel]
+		return h[len(h)+idx]
+	}
+	if idx >= in.Length() {
+		return 0
+	}
+	return in.Sample(in.BufferIndex(channel, idx))
+}
+
+// keep shifts input samples into the history.
+func (r *Resampler) keep(in signal.Floating) {
+	n := in.Length()
+	for c, h := range r.history {
+		size := len(h)
+		if n < size {
+			copy(h, h[n:])
+		}
+		for i := 0; i < size && i < n; i++ {
+			h[size-1-i] = in.Sample(in.BufferIndex(c, n-1-i))
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package resample_test
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"pipelined.dev/signal"
+
+	"pipelined.dev/pipe/internal/resample"
+)
+
+func TestResampler(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		resampler  *resample.Resampler
+		latency    int
+		tail       int
+		length     int
+		bufferSize int
+	}{
+		{"linear upsample", resample.Linear(1, 44100, 48000), 0, 1, 557, 559},
+		{"linear downsample", resample.Linear(1, 48000, 24000), 0, 1, 256, 258},
+		{"sinc same rate", resample.Sinc(1, 48000, 48000), 16, 16, 513, 514},
+		{"sinc upsample", resample.Sinc(1, 44100, 48000), 18, 16, 558, 559},
+		// cutoff is halved, so the kernel is twice longer.
+		{"sinc downsample", resample.Sinc(1, 96000, 48000), 16, 32, 257, 258},
+	} {
+		assertEqual(t, test.name+" latency", test.resampler.Latency(), test.latency)
+		assertEqual(t, test.name+" tail", test.resampler.Tail(), test.tail)
+		assertEqual(t, test.name+" length", test.resampler.Length(512), test.length)
+		assertEqual(t, test.name+" buffer size", test.resampler.BufferSize(512), test.bufferSize)
+	}
+}
+
+func TestProcess(t *testing.T) {
+	// process resamples input in buffers of provided size.
+	process := func(r *resample.Resampler, input []float64, bufferSize int) []float64 {
+		var result []float64
+		for start := 0; start < len(input); start += bufferSize {
+			end := start + bufferSize
+			if end > len(input) {
+				end = len(input)
+			}
+			in := signal.Allocator{Channels: 1, Length: end - start, Capacity: end - start}.Float64()
+			signal.WriteFloat64(input[start:end], in)
+			length := r.Length(in.Length())
+			out := signal.Allocator{Channels: 1, Length: length, Capacity: length}.Float64()
+			r.Process(in, out)
+			values := make([]float64, length)
+			signal.ReadFloat64(out, values)
+			result = append(result, values...)
+		}
+		return result
+	}
+	ramp := func(n int) []float64 {
+		values := make([]float64, n)
+		for i := range values {
+			values[i] = float64(i)
+		}
+		return values
+	}
+
+	for _, test := range []struct {
+		name       string
+		resampler  *resample.Resampler
+		input      []float64
+		bufferSize int
+		expected   []float64
+	}{
+		{"linear same rate", resample.Linear(1, 8000, 8000), ramp(6), 4, ramp(6)},
+		{"linear upsample", resample.Linear(1, 8000, 16000), ramp(4), 4, []float64{0, 0.5, 1, 1.5, 2, 2.5, 3}},
+		// samples between buffers are interpolated from the history.
+		{"linear upsample buffers", resample.Linear(1, 8000, 16000), ramp(4), 2, []float64{0, 0.5, 1, 1.5, 2, 2.5, 3}},
+		{"linear downsample", resample.Linear(1, 16000, 8000), ramp(8), 3, []float64{0, 2, 4, 6}},
+	} {
+		assertEqual(t, test.name, process(test.resampler, test.input, test.bufferSize), test.expected)
+	}
+
+	t.Run("sinc delay", func(t *testing.T) {
+		r := resample.Sinc(1, 48000, 48000)
+		impulse := make([]float64, 64)
+		impulse[0] = 1
+		result := process(r, impulse, 16)
+		for i, v := range result {
+			expected := 0.0
+			if i == r.Latency() {
+				expected = 1
+			}
+			assertNear(t, "impulse", v, expected, 1e-9)
+		}
+	})
+	t.Run("sinc downsample", func(t *testing.T) {
+		r := resample.Sinc(1, 96000, 48000)
+		constant := make([]float64, 512)
+		for i := range constant {
+			constant[i] = 0.5
+		}
+		result := process(r, constant, 100)
+		// output after the latency doesn't depend on the silent history.
+		for _, v := range result[2*r.Latency():] {
+			assertNear(t, "constant", v, 0.5, 1e-9)
+		}
+	})
+}
+
+func assertEqual(t *testing.T, name string, result, expected interface{}) {
+	t.Helper()
+	if !reflect.DeepEqual(expected, result) {
+		t.Fatalf("%v\nresult: \t%T\t%+v \nexpected: \t%T\t%+v", name, result, result, expected, expected)
+	}
+}
+
+func assertNear(t *testing.T, name string, result, expected, delta float64) {
+	t.Helper()
+	if math.Abs(result-expected) > delta {
+		t.Fatalf("%v\nresult: \t%v\nexpected: \t%v", name, result, expected)
+	}
+}
@@ -0,0 +1,91 @@
+package runner
+
+import (
+	"fmt"
+
+	"pipelined.dev/signal"
+
+	"pipelined.dev/pipe/mutability"
+)
+
+// Bypass is the bypass state of the processor. It's changed by
+// mutations of its own mutability, so processor doesn't need to be
+// mutable and the state is only accessed from the runner goroutine.
+type Bypass struct {
+	Mutability mutability.Mutability
+	enabled    bool
+	crossfade  bool
+}
+
+// Set enables or disables the bypass. If crossfade is true, the next
+// buffer crossfades between processed and input signals.
+func (b *Bypass) Set(enabled, crossfade bool) {
+	if b.enabled == enabled {
+		return
+	}
+	b.enabled = enabled
+	b.crossfade = crossfade
+}
+
+// active returns true if processor output is affected by bypass.
+func (b *Bypass) active() bool {
+	return b != nil && (b.enabled || b.crossfade)
+}
+
+// bypass passes the input buffer through the processor without
+// processing. If pools have the same dimensions and sample types are the
+// same, the input buffer is returned, otherwise it's copied. If crossfade is pending, processor
+// is executed and the result is mixed with the input.
+func (r Processor) bypass(in signal.Signal) (signal.Signal, error) {
+	if !r.Bypass.crossfade && samePool(r.InPool, r.OutPool) && r.InType == r.OutType {
+		return in, nil
+	}
+	out := r.OutType.get(r.OutPool)
+	if in.Length() != out.Length() {
+		out = slice(out, 0, in.Length())
+	}
+	if r.Bypass.crossfade {
+		r.Bypass.crossfade = false
+		if err := r.Fn(in, out); err != nil {
+			in.Free(r.InPool)
+			out.Free(r.OutPool)
+			return nil, fmt.Errorf("error running processor: %w", err)
+		}
+		crossfade(out, in, r.Bypass.enabled)
+	} else {
+		Copy(in, out)
+	}
+	in.Free(r.InPool)
+	return out, nil
+}
+
+// samePool returns true if buffers of pools are interchangeable. Pools
+// are allocated per component, so they are compared by dimensions.
+func samePool(a, b *signal.PoolAllocator) bool {
+	return a.Channels == b.Channels && a.Length == b.Length && a.Capacity == b.Capacity
+}
+
+// crossfade mixes processed and input signals into processed buffer
+// over its length. If toDry is true, the mix goes from processed to
+// input signal, otherwise from input to processed one.
+func crossfade(wet, dry signal.Signal, toDry bool) {
+	length := wet.Length()
+	channels := wet.Channels()
+	for i := 0; i < length; i++ {
+		g := float64(i+1) / float64(length)
+		if !toDry {
+			g = 1 - g
+		}
+		for c := 0; c < channels; c++ {
+			idx := wet.BufferIndex(c, i)
+			switch wet := wet.(type) {
+			case signal.Floating:
+				w, d := wet.Sample(idx), dry.(signal.Floating).Sample(idx)
+				wet.SetSample(idx, w+(d-w)*g)
+			case signal.Signed:
+				w, d := wet.Sample(idx), dry.(signal.Signed).Sample(idx)
+				wet.SetSample(idx, w+int64(float64(d-w)*g))
+			}
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"pipelined.dev/signal"
+
+	"pipelined.dev/pipe/mutability"
+)
+
+// Line executes components of a single line synchronously on the
+// caller's goroutine.
+type Line struct {
+	Source     Source
+	Processors []Processor
+	Sink       Sink
+
+	// state of the processors tails after the source is done.
+	done      bool
+	tail      int
+	remaining int
+}
+
+// Step pulls a single buffer from the source, processes it and pushes
+// the result into the sink. Mutations are applied to each component
+// right before its execution. After the source is done, the tails of
+// processors are pushed. When tails are done, io.EOF is returned.
+func (l *Line) Step(mutations mutability.Mutations) error {
+	message, err := l.next(mutations)
+	if err != nil {
+		return err
+	}
+	return l.Sink.sink(message)
+}
+
+// next returns the next processed message. After the source is done,
+// processors receive silent buffers until their tails are done.
+func (l *Line) next(mutations mutability.Mutations) (Message, error) {
+	var (
+		message = Message{Mutations: mutations}
+		err     error
+	)
+	if !l.done {
+		if message.Signal, err = l.Source.source(mutations); err == nil {
+			message.Signal, err = Fused(l.Processors).process(0, message)
+			return message, err
+		}
+		if err != io.EOF {
+			return Message{}, err
+		}
+		l.done = true
+		l.tail = -1
+	}
+	for l.remaining == 0 {
+		if l.tail++; l.tail == len(l.Processors) {
+			return Message{}, io.EOF
+		}
+		l.remaining = l.Processors[l.tail].Tail
+	}
+	message.Signal, l.remaining = l.Processors[l.tail].silence(l.remaining)
+	message.Signal, err = Fused(l.Processors).process(l.tail, message)
+	return message, err
+}
+
+// Flush calls flush hooks of all line components. All hooks are called
+// even if some of them failed, the first error is returned.
+func (l Line) Flush(ctx context.Context) error {
+	var flushErr error
+	if err := l.Source.Flush.call(ctx); err != nil {
+		flushErr = fmt.Errorf("error flushing source: %w", err)
+	}
+	if err := Fused(l.Processors).flush(ctx); err != nil && flushErr == nil {
+		flushErr = err
+	}
+	if err := l.Sink.Flush.call(ctx); err != nil && flushErr == nil {
+		flushErr = fmt.Errorf("error flushing sink: %w", err)
+	}
+	return flushErr
+}
+
+// Pull pulls a single buffer from the source through processors and
+// writes the result into provided buffer. Samples are converted if the
+// line has different sample type. If sink is defined, it receives the
+// same signal. Returns the number of samples per channel written into
+// the buffer. When the source and processors tails are done, io.EOF is
+// returned.
+func (l *Line) Pull(mutations mutability.Mutations, out signal.Floating) (int, error) {
+	message, err := l.next(mutations)
+	if err != nil {
+		return 0, err
+	}
+	n := Copy(message.Signal, out)
+	if l.Sink.Fn != nil {
+		return n, l.Sink.sink(message)
+	}
+	message.Signal.Free(l.outPool())
+	return n, nil
+}
+
+// outPool returns the pool of the line output buffers.
+func (l Line) outPool() *signal.PoolAllocator {
+	if len(l.Processors) > 0 {
+		return l.Processors[len(l.Processors)-1].OutPool
+	}
+	return l.Source.OutPool
+}
@@ -0,0 +1,181 @@
+package runner
+
+import (
+	"context"
+	"runtime"
+	"sync/atomic"
+)
+
+type (
+	// Sender sends messages to the next runner. Send returns false if
+	// message wasn't sent because context is done.
+	Sender interface {
+		Send(context.Context, Message) bool
+		Close()
+	}
+
+	// Receiver receives messages from the previous runner. Receive
+	// returns false if sender is closed or context is done.
+	Receiver interface {
+		Receive(context.Context) (Message, bool)
+	}
+
+	// Link is a transport between two runners.
+	Link interface {
+		Sender
+		Receiver
+	}
+)
+
+// Chan is a Link based on go channel.
+type Chan chan Message
+
+// receiver is a Receiver based on receive-only go channel.
+type receiver <-chan Message
+
+// Send message into the channel.
+func (c Chan) Send(ctx context.Context, m Message) bool {
+	select {
+	case c <- m:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Receive message from the channel.
+func (c Chan) Receive(ctx context.Context) (Message, bool) {
+	var in <-chan Message = c
+	return receiver(in).Receive(ctx)
+}
+
+// Close the channel.
+func (c Chan) Close() {
+	close(c)
+}
+
+// Receive message from the channel.
+func (c receiver) Receive(ctx context.Context) (Message, bool) {
+	select {
+	case m, ok := <-c:
+		return m, ok
+	case <-ctx.Done():
+		return Message{}, false
+	}
+}
+
+// spins is the number of attempts Ring makes before parking.
+const spins = 64
+
+// Ring is a lock-free single-producer single-consumer Link. When the
+// ring is empty or full, the waiting side spins for a while and parks
+// afterwards.
+type Ring struct {
+	head    uint64 // next slot to read, written by consumer.
+	tail    uint64 // next slot to write, written by producer.
+	closed  uint32
+	reading uint32 // consumer is parked.
+	writing uint32 // producer is parked.
+
+	mask     uint64
+	slots    []Message
+	readable chan struct{}
+	writable chan struct{}
+}
+
+// NewRing returns a new Ring with provided number of slots. The number
+// is rounded up to the nearest power of two.
+func NewRing(slots int) *Ring {
+	size := 1
+	for size < slots {
+		size <<= 1
+	}
+	return &Ring{
+		mask:     uint64(size - 1),
+		slots:    make([]Message, size),
+		readable: make(chan struct{}, 1),
+		writable: make(chan struct{}, 1),
+	}
+}
+
+// Send message into the ring. Blocks while the ring is full.
+func (r *Ring) Send(ctx context.Context, m Message) bool {
+	tail := atomic.LoadUint64(&r.tail)
+	for i := 0; tail-atomic.LoadUint64(&r.head) > r.mask; i++ {
+		if i < spins {
+			runtime.Gosched()
+			continue
+		}
+		if !r.park(ctx, &r.writing, r.writable, func() bool {
+			return tail-atomic.LoadUint64(&r.head) <= r.mask
+		}) {
+			return false
+		}
+	}
+	r.slots[tail&r.mask] = m
+	atomic.StoreUint64(&r.tail, tail+1)
+	wake(&r.reading, r.readable)
+	return true
+}
+
+// Receive message from the ring. Blocks while the ring is empty.
+func (r *Ring) Receive(ctx context.Context) (Message, bool) {
+	head := atomic.LoadUint64(&r.head)
+	for i := 0; head == atomic.LoadUint64(&r.tail); i++ {
+		if atomic.LoadUint32(&r.closed) == 1 {
+			// messages sent before close must be delivered.
+			if head == atomic.LoadUint64(&r.tail) {
+				return Message{}, false
+			}
+			break
+		}
+		if i < spins {
+			runtime.Gosched()
+			continue
+		}
+		if !r.park(ctx, &r.reading, r.readable, func() bool {
+			return head != atomic.LoadUint64(&r.tail) || atomic.LoadUint32(&r.closed) == 1
+		}) {
+			return Message{}, false
+		}
+	}
+	m := r.slots[head&r.mask]
+	r.slots[head&r.mask] = Message{}
+	atomic.StoreUint64(&r.head, head+1)
+	wake(&r.writing, r.writable)
+	return m, true
+}
+
+// Close the ring. Messages sent before close are still delivered.
+func (r *Ring) Close() {
+	atomic.StoreUint32(&r.closed, 1)
+	wake(&r.reading, r.readable)
+}
+
+// park blocks until the other side wakes it up or context is done. The
+// parked flag is set before the ready condition is checked again, so
+// wake up cannot be missed. Returns false if context is done.
+func (r *Ring) park(ctx context.Context, parked *uint32, wakeup chan struct{}, ready func() bool) bool {
+	atomic.StoreUint32(parked, 1)
+	if ready() {
+		atomic.StoreUint32(parked, 0)
+		return true
+	}
+	select {
+	case <-wakeup:
+		return true
+	case <-ctx.Done():
+		atomic.StoreUint32(parked, 0)
+		return false
+	}
+}
+
+// wake up the parked side if there is one.
+func wake(parked *uint32, wakeup chan struct{}) {
+	if atomic.CompareAndSwapUint32(parked, 1, 0) {
+		select {
+		case wakeup <- struct{}{}:
+		default:
+		}
+	}
+}
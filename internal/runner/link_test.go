@@ -0,0 +1,50 @@
+package runner_test
+
+import (
+	"context"
+	"testing"
+
+	"pipelined.dev/signal"
+
+	"pipelined.dev/pipe/internal/runner"
+)
+
+func TestRing(t *testing.T) {
+	testRing := func(slots, messages int) func(*testing.T) {
+		return func(t *testing.T) {
+			t.Helper()
+			ring := runner.NewRing(slots)
+			go func() {
+				defer ring.Close()
+				for i := 0; i < messages; i++ {
+					s := signal.Allocator{Channels: 1, Length: 1, Capacity: 1}.Float64()
+					s.SetSample(0, float64(i))
+					ring.Send(context.Background(), runner.Message{Signal: s})
+				}
+			}()
+			received := 0
+			for {
+				m, ok := ring.Receive(context.Background())
+				if !ok {
+					break
+				}
+				assertEqual(t, "order", m.Signal.(signal.Floating).Sample(0), float64(received))
+				received++
+			}
+			assertEqual(t, "received", received, messages)
+		}
+	}
+	testContextDone := func(t *testing.T) {
+		ctx, cancelFn := context.WithCancel(context.Background())
+		cancelFn()
+		ring := runner.NewRing(1)
+		_, ok := ring.Receive(ctx)
+		assertEqual(t, "receive", ok, false)
+		assertEqual(t, "send", ring.Send(ctx, runner.Message{}), true)
+		assertEqual(t, "send full", ring.Send(ctx, runner.Message{}), false)
+	}
+	t.Run("1 slot", testRing(1, 1000))
+	t.Run("3 slots", testRing(3, 1000))
+	t.Run("empty", testRing(4, 0))
+	t.Run("context done", testContextDone)
+}
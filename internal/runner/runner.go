@@ -12,34 +12,54 @@ import (
 
 // Message is a main structure for pipe transport
 type Message struct {
-	Signal               signal.Floating // Buffer of message.
-	mutability.Mutations                 // Mutators for pipe.
+	Signal               signal.Signal // Buffer of message.
+	mutability.Mutations               // Mutators for pipe.
 }
 
 type (
-	// Source executes pipe.Source components.
+	// Source executes pipe.Source components. OutType is the sample
+	// type of output buffers.
 	Source struct {
 		Mutability [16]byte
 		Flush
 		OutPool *signal.PoolAllocator
-		Fn      func(out signal.Floating) (int, error)
+		OutType SampleType
+		Fn      func(out signal.Signal) (int, error)
 	}
 
-	// Processor executes pipe.Processor components.
+	// Processor executes pipe.Processor components. Output buffer has
+	// the same length as input one. If Length is defined, it returns
+	// the length of output buffer for provided input length instead.
+	// Tail is the number of silent samples per channel
+	// that processor receives after the end of input. InType and
+	// OutType are sample types of input and output buffers. If Bypass
+	// is enabled, the input is passed through without processing.
+	// Nested are mutabilities of components executed by Fn, their
+	// mutations are applied along with processor ones.
 	Processor struct {
 		Mutability [16]byte
+		Nested     []mutability.Mutability
 		Flush
 		InPool  *signal.PoolAllocator
 		OutPool *signal.PoolAllocator
-		Fn      func(in, out signal.Floating) error
+		InType  SampleType
+		OutType SampleType
+		Fn      func(in, out signal.Signal) error
+		Length  func(int) int
+		Tail    int
+		Bypass  *Bypass
 	}
 
+	// Fused executes multiple pipe.Processor components in a single
+	// goroutine.
+	Fused []Processor
+
 	// Sink executes pipe.Sink components.
 	Sink struct {
 		Mutability [16]byte
 		Flush
 		InPool *signal.PoolAllocator
-		Fn     func(in signal.Floating) error
+		Fn     func(in signal.Signal) error
 	}
 )
 
@@ -55,10 +75,16 @@ func (fn Flush) call(ctx context.Context) error {
 
 // Run starts the Source runner.
 func (r Source) Run(ctx context.Context, mutationsChan chan mutability.Mutations) (<-chan Message, <-chan error) {
-	out := make(chan Message, 1)
+	out := make(Chan, 1)
+	return out, r.Start(ctx, mutationsChan, out)
+}
+
+// Start starts the Source runner. Messages are sent into provided
+// sender, which is closed when runner is done.
+func (r Source) Start(ctx context.Context, mutationsChan chan mutability.Mutations, out Sender) <-chan error {
 	errs := make(chan error, 1)
 	go func() {
-		defer close(out)
+		defer out.Close()
 		defer close(errs)
 		// flush on return
 		defer func() {
@@ -67,9 +93,8 @@ func (r Source) Run(ctx context.Context, mutationsChan chan mutability.Mutations
 			}
 		}()
 		var (
-			read      int
 			mutations mutability.Mutations
-			outSignal signal.Floating
+			outSignal signal.Signal
 			err       error
 		)
 		for {
@@ -80,41 +105,58 @@ func (r Source) Run(ctx context.Context, mutationsChan chan mutability.Mutations
 			default:
 			}
 
-			if err = mutations.ApplyTo(r.Mutability); err != nil {
-				errs <- fmt.Errorf("error mutating source: %w", err)
-				return
-			}
-
-			outSignal = r.OutPool.GetFloat64()
-			if read, err = r.Fn(outSignal); err != nil {
+			if outSignal, err = r.source(mutations); err != nil {
 				if err != io.EOF {
-					errs <- fmt.Errorf("error running source: %w", err)
+					errs <- err
 				}
-				// this buffer wasn't sent, free now
-				outSignal.Free(r.OutPool)
 				return
 			}
-			if read != outSignal.Length() {
-				outSignal = outSignal.Slice(0, read)
-			}
 
-			select {
-			case out <- Message{Mutations: mutations, Signal: outSignal}:
-				mutations = nil
-			case <-ctx.Done():
+			if !out.Send(ctx, Message{Mutations: mutations, Signal: outSignal}) {
 				return
 			}
+			mutations = nil
 		}
 	}()
-	return out, errs
+	return errs
+}
+
+// source applies mutations and reads a single buffer from the source. If
+// source is done, io.EOF is returned.
+func (r Source) source(mutations mutability.Mutations) (signal.Signal, error) {
+	if err := mutations.ApplyTo(r.Mutability); err != nil {
+		return nil, fmt.Errorf("error mutating source: %w", err)
+	}
+
+	outSignal := r.OutType.get(r.OutPool)
+	read, err := r.Fn(outSignal)
+	if err != nil {
+		// this buffer wasn't sent, free now
+		outSignal.Free(r.OutPool)
+		if err == io.EOF {
+			return nil, err
+		}
+		return nil, fmt.Errorf("error running source: %w", err)
+	}
+	if read != outSignal.Length() {
+		outSignal = slice(outSignal, 0, read)
+	}
+	return outSignal, nil
 }
 
 // Run starts the Processor runner.
 func (r Processor) Run(ctx context.Context, in <-chan Message) (<-chan Message, <-chan error) {
+	out := make(Chan, 1)
+	return out, r.Start(ctx, receiver(in), out)
+}
+
+// Start starts the Processor runner. Messages are received from provided
+// receiver and sent into provided sender, which is closed when runner is
+// done.
+func (r Processor) Start(ctx context.Context, in Receiver, out Sender) <-chan error {
 	errs := make(chan error, 1)
-	out := make(chan Message, 1)
 	go func() {
-		defer close(out)
+		defer out.Close()
 		defer close(errs)
 		// flush on return
 		defer func() {
@@ -124,48 +166,191 @@ func (r Processor) Run(ctx context.Context, in <-chan Message) (<-chan Message,
 		}()
 		var (
 			message   Message
-			outSignal signal.Floating
+			outSignal signal.Signal
 			ok        bool
 			err       error
 		)
 		for {
-			select {
-			case message, ok = <-in:
-				if !ok {
-					return
-				}
-			case <-ctx.Done():
+			if message, ok = in.Receive(ctx); !ok {
+				break
+			}
+
+			if outSignal, err = r.process(message); err != nil {
+				errs <- err
 				return
 			}
 
-			if err = message.Mutations.ApplyTo(r.Mutability); err != nil {
-				errs <- fmt.Errorf("error mutating processor: %w", err)
-				message.Signal.Free(r.InPool)
+			if !out.Send(ctx, Message{Mutations: message.Mutations, Signal: outSignal}) {
+				return
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		// input is done, feed the tail
+		for remaining := r.Tail; remaining > 0; {
+			message = Message{}
+			message.Signal, remaining = r.silence(remaining)
+			if outSignal, err = r.process(message); err != nil {
+				errs <- err
 				return
 			}
 
-			outSignal = r.OutPool.GetFloat64()
-			err = r.Fn(message.Signal, outSignal)
+			if !out.Send(ctx, Message{Signal: outSignal}) {
+				return
+			}
+		}
+	}()
+	return errs
+}
+
+// process applies mutations and executes processor for a single message.
+// Input buffer is freed after the call. If error is returned, output
+// buffer is already freed.
+func (r Processor) process(message Message) (signal.Signal, error) {
+	if err := message.Mutations.ApplyTo(r.Mutability); err != nil {
+		message.Signal.Free(r.InPool)
+		return nil, fmt.Errorf("error mutating processor: %w", err)
+	}
+	for _, id := range r.Nested {
+		if err := message.Mutations.ApplyTo(id); err != nil {
 			message.Signal.Free(r.InPool)
-			if err != nil {
-				errs <- fmt.Errorf("error running processor: %w", err)
-				// this buffer wasn't sent, free now
-				outSignal.Free(r.OutPool)
+			return nil, fmt.Errorf("error mutating processor: %w", err)
+		}
+	}
+	if r.Bypass != nil {
+		if err := message.Mutations.ApplyTo(r.Bypass.Mutability); err != nil {
+			message.Signal.Free(r.InPool)
+			return nil, fmt.Errorf("error bypassing processor: %w", err)
+		}
+	}
+	if r.Bypass.active() {
+		return r.bypass(message.Signal)
+	}
+
+	length := message.Signal.Length()
+	if r.Length != nil {
+		length = r.Length(length)
+	}
+	outSignal := r.OutType.get(r.OutPool)
+	if length != outSignal.Length() {
+		outSignal = slice(outSignal, 0, length)
+	}
+	err := r.Fn(message.Signal, outSignal)
+	message.Signal.Free(r.InPool)
+	if err != nil {
+		// this buffer wasn't sent, free now
+		outSignal.Free(r.OutPool)
+		return nil, fmt.Errorf("error running processor: %w", err)
+	}
+	return outSignal, nil
+}
+
+// silence returns the next silent input buffer of the processor tail
+// and the remaining length of the tail.
+func (r Processor) silence(remaining int) (signal.Signal, int) {
+	in := r.InType.get(r.InPool)
+	if remaining < in.Length() {
+		in = slice(in, 0, remaining)
+	}
+	silence(in)
+	return in, remaining - in.Length()
+}
+
+// Run starts the Fused runner.
+func (r Fused) Run(ctx context.Context, in <-chan Message) (<-chan Message, <-chan error) {
+	out := make(Chan, 1)
+	return out, r.Start(ctx, receiver(in), out)
+}
+
+// Start starts the Fused runner. Processors are executed sequentially,
+// output of each processor is the input of the next one. Mutations are
+// delivered to each processor before it's executed.
+func (r Fused) Start(ctx context.Context, in Receiver, out Sender) <-chan error {
+	errs := make(chan error, 1)
+	go func() {
+		defer out.Close()
+		defer close(errs)
+		// flush on return
+		defer func() {
+			if err := r.flush(ctx); err != nil {
+				errs <- err
+			}
+		}()
+		var (
+			message Message
+			ok      bool
+			err     error
+		)
+		for {
+			if message, ok = in.Receive(ctx); !ok {
+				break
+			}
+
+			if message.Signal, err = r.process(0, message); err != nil {
+				errs <- err
 				return
 			}
 
-			select {
-			case out <- Message{Mutations: message.Mutations, Signal: outSignal}:
-			case <-ctx.Done():
+			if !out.Send(ctx, message) {
 				return
 			}
 		}
+		if ctx.Err() != nil {
+			return
+		}
+		// input is done, feed the tails. Tail of each processor is
+		// passed through the following processors.
+		for i := range r {
+			for remaining := r[i].Tail; remaining > 0; {
+				message = Message{}
+				message.Signal, remaining = r[i].silence(remaining)
+				if message.Signal, err = r.process(i, message); err != nil {
+					errs <- err
+					return
+				}
+
+				if !out.Send(ctx, message) {
+					return
+				}
+			}
+		}
 	}()
-	return out, errs
+	return errs
+}
+
+// process executes processors starting from provided index for a single
+// message.
+func (r Fused) process(from int, message Message) (signal.Signal, error) {
+	var err error
+	for i := from; i < len(r); i++ {
+		if message.Signal, err = r[i].process(message); err != nil {
+			return nil, err
+		}
+	}
+	return message.Signal, nil
+}
+
+// flush calls flush hooks of all fused processors. The first error is
+// returned.
+func (r Fused) flush(ctx context.Context) error {
+	var flushErr error
+	for i := range r {
+		if err := r[i].Flush.call(ctx); err != nil && flushErr == nil {
+			flushErr = fmt.Errorf("error flushing processor: %w", err)
+		}
+	}
+	return flushErr
 }
 
 // Run starts the sink runner.
 func (r Sink) Run(ctx context.Context, in <-chan Message) <-chan error {
+	return r.Start(ctx, receiver(in))
+}
+
+// Start starts the sink runner. Messages are received from provided
+// receiver.
+func (r Sink) Start(ctx context.Context, in Receiver) <-chan error {
 	errs := make(chan error, 1)
 	go func() {
 		defer close(errs)
@@ -182,25 +367,12 @@ func (r Sink) Run(ctx context.Context, in <-chan Message) <-chan error {
 		)
 		for {
 			// receive new message
-			select {
-			case message, ok = <-in:
-				if !ok {
-					return
-				}
-			case <-ctx.Done():
+			if message, ok = in.Receive(ctx); !ok {
 				return
 			}
 
-			// apply Mutators
-			if err = message.Mutations.ApplyTo(r.Mutability); err != nil {
-				errs <- fmt.Errorf("error mutating sink: %w", err)
-				message.Signal.Free(r.InPool) // need to free
-				return
-			}
-			err = r.Fn(message.Signal) // sink a buffer
-			message.Signal.Free(r.InPool)
-			if err != nil {
-				errs <- fmt.Errorf("error running sink: %w", err)
+			if err = r.sink(message); err != nil {
+				errs <- err
 				return
 			}
 		}
@@ -208,3 +380,19 @@ func (r Sink) Run(ctx context.Context, in <-chan Message) <-chan error {
 
 	return errs
 }
+
+// sink applies mutations and sinks a single message. Input buffer is
+// freed after the call.
+func (r Sink) sink(message Message) error {
+	// apply Mutators
+	if err := message.Mutations.ApplyTo(r.Mutability); err != nil {
+		message.Signal.Free(r.InPool) // need to free
+		return fmt.Errorf("error mutating sink: %w", err)
+	}
+	err := r.Fn(message.Signal) // sink a buffer
+	message.Signal.Free(r.InPool)
+	if err != nil {
+		return fmt.Errorf("error running sink: %w", err)
+	}
+	return nil
+}
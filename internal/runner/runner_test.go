@@ -3,6 +3,7 @@ package runner_test
 import (
 	"context"
 	"errors"
+	"io"
 	"reflect"
 	"testing"
 
@@ -27,7 +28,7 @@ func TestSource(t *testing.T) {
 		return runner.Source{
 			Mutability: source.Mutability,
 			OutPool:    signal.GetPoolAllocator(props.Channels, bufferSize, bufferSize),
-			Fn:         source.SourceFunc,
+			Fn:         runner.FloatingSource(source.SourceFunc),
 			Flush:      runner.Flush(source.FlushFunc),
 		}
 	}
@@ -123,7 +124,7 @@ func TestProcessor(t *testing.T) {
 			Mutability: processor.Mutability,
 			InPool:     signal.GetPoolAllocator(props.Channels, bufferSize, bufferSize),
 			OutPool:    signal.GetPoolAllocator(props.Channels, bufferSize, bufferSize),
-			Fn:         processor.ProcessFunc,
+			Fn:         runner.FloatingProcessor(processor.ProcessFunc),
 			Flush:      runner.Flush(processor.FlushFunc),
 		}
 	}
@@ -218,13 +219,160 @@ func TestProcessor(t *testing.T) {
 	))
 }
 
+func TestProcessorPartialBuffer(t *testing.T) {
+	pool := signal.GetPoolAllocator(channels, bufferSize, bufferSize)
+	processor, _, _ := (&mock.Processor{}).Processor()(bufferSize, pipe.SignalProperties{Channels: channels})
+	r := runner.Processor{
+		InPool:  pool,
+		OutPool: pool,
+		Fn:      runner.FloatingProcessor(processor.ProcessFunc),
+	}
+	in := make(chan runner.Message, 1)
+	out, errc := r.Run(context.Background(), in)
+	in <- runner.Message{
+		Signal: pool.GetFloat64().Slice(0, bufferSize/3),
+	}
+	close(in)
+	for msg := range out {
+		assertEqual(t, "samples", msg.Signal.Length(), bufferSize/3)
+	}
+	for err := range errc {
+		assertEqual(t, "error", err, nil)
+	}
+}
+
+func TestProcessorBypass(t *testing.T) {
+	bypass := &runner.Bypass{}
+	bypass.Set(true, false)
+	r := runner.Processor{
+		InPool:  signal.GetPoolAllocator(channels, bufferSize, bufferSize),
+		OutPool: signal.GetPoolAllocator(channels, bufferSize, bufferSize),
+		Fn: func(in, out signal.Signal) error {
+			return testError
+		},
+		Bypass: bypass,
+	}
+	in := make(chan runner.Message, 1)
+	out, errc := r.Run(context.Background(), in)
+	buffer := r.InPool.GetFloat64()
+	buffer.SetSample(0, 0.5)
+	in <- runner.Message{
+		Signal: buffer,
+	}
+	close(in)
+	for msg := range out {
+		assertEqual(t, "passed through", msg.Signal == signal.Signal(buffer), true)
+		assertEqual(t, "unchanged", buffer.Sample(0), 0.5)
+	}
+	for err := range errc {
+		assertEqual(t, "error", err, nil)
+	}
+}
+
+func TestFused(t *testing.T) {
+	setupRunner := func(processorAllocators ...pipe.ProcessorAllocatorFunc) runner.Fused {
+		var r runner.Fused
+		for _, fn := range processorAllocators {
+			processor, props, _ := fn(bufferSize, pipe.SignalProperties{Channels: channels})
+			r = append(r, runner.Processor{
+				Mutability: processor.Mutability,
+				InPool:     signal.GetPoolAllocator(props.Channels, bufferSize, bufferSize),
+				OutPool:    signal.GetPoolAllocator(props.Channels, bufferSize, bufferSize),
+				Fn:         runner.FloatingProcessor(processor.ProcessFunc),
+				Flush:      runner.Flush(processor.FlushFunc),
+			})
+		}
+		return r
+	}
+	testFused := func(ctx context.Context, mockProcessors ...*mock.Processor) func(*testing.T) {
+		return func(t *testing.T) {
+			t.Helper()
+			alloc := signal.Allocator{
+				Channels: channels,
+				Length:   bufferSize,
+				Capacity: bufferSize,
+			}
+			var (
+				allocators []pipe.ProcessorAllocatorFunc
+				mutations  mutability.Mutations
+			)
+			for _, p := range mockProcessors {
+				allocators = append(allocators, p.Processor())
+				if !p.Mutability.Immutable() {
+					mutations = mutations.Put(p.MockMutation())
+				}
+			}
+			in := make(chan runner.Message, 1)
+			out, errc := setupRunner(allocators...).Run(ctx, in)
+
+			in <- runner.Message{
+				Signal:    alloc.Float64(),
+				Mutations: mutations,
+			}
+			close(in)
+			for msg := range out {
+				assertEqual(t, "samples", msg.Signal.Length(), alloc.Length)
+			}
+			for err := range errc {
+				assertEqual(t, "error", errors.Unwrap(err), testError)
+			}
+			for _, p := range mockProcessors {
+				assertEqual(t, "flushed", p.Flusher.Flushed, true)
+			}
+			return
+		}
+	}
+	t.Run("ok", testFused(
+		context.Background(),
+		&mock.Processor{
+			Mutator: mock.Mutator{
+				Mutability: mutability.Mutable(),
+			},
+		},
+		&mock.Processor{
+			Mutator: mock.Mutator{
+				Mutability: mutability.Mutable(),
+			},
+		},
+	))
+	t.Run("error", testFused(
+		context.Background(),
+		&mock.Processor{
+			Mutator: mock.Mutator{
+				Mutability: mutability.Mutable(),
+			},
+			ErrorOnCall: testError,
+		},
+		&mock.Processor{},
+	))
+	t.Run("flush error", testFused(
+		context.Background(),
+		&mock.Processor{},
+		&mock.Processor{
+			Flusher: mock.Flusher{
+				ErrorOnFlush: testError,
+			},
+		},
+	))
+	t.Run("mutation error", testFused(
+		context.Background(),
+		&mock.Processor{},
+		&mock.Processor{
+			Mutator: mock.Mutator{
+				Mutability:      mutability.Mutable(),
+				ErrorOnMutation: testError,
+			},
+		},
+	))
+}
+
 func TestSink(t *testing.T) {
 	setupRunner := func(sinkAllocator pipe.SinkAllocatorFunc, alloc signal.Allocator) runner.Sink {
 		sink, _ := sinkAllocator(bufferSize, pipe.SignalProperties{Channels: channels})
 		return runner.Sink{
 			Mutability: sink.Mutability,
 			InPool:     signal.GetPoolAllocator(channels, bufferSize, bufferSize),
-			Fn:         sink.SinkFunc,
+			Fn:         runner.FloatingSink(sink.SinkFunc),
 			Flush:      runner.Flush(sink.FlushFunc),
 		}
 	}
@@ -312,6 +460,119 @@ func TestSink(t *testing.T) {
 	))
 }
 
+func TestLine(t *testing.T) {
+	setupLine := func(source *mock.Source, processor *mock.Processor, sink *mock.Sink) runner.Line {
+		src, props, _ := source.Source()(bufferSize)
+		proc, props, _ := processor.Processor()(bufferSize, props)
+		snk, _ := sink.Sink()(bufferSize, props)
+		pool := signal.GetPoolAllocator(props.Channels, bufferSize, bufferSize)
+		return runner.Line{
+			Source: runner.Source{
+				Mutability: src.Mutability,
+				OutPool:    pool,
+				Fn:         runner.FloatingSource(src.SourceFunc),
+				Flush:      runner.Flush(src.FlushFunc),
+			},
+			Processors: []runner.Processor{
+				{
+					Mutability: proc.Mutability,
+					InPool:     pool,
+					OutPool:    pool,
+					Fn:         runner.FloatingProcessor(proc.ProcessFunc),
+					Flush:      runner.Flush(proc.FlushFunc),
+					Tail:       proc.Tail,
+				},
+			},
+			Sink: runner.Sink{
+				Mutability: snk.Mutability,
+				InPool:     pool,
+				Fn:         runner.FloatingSink(snk.SinkFunc),
+				Flush:      runner.Flush(snk.FlushFunc),
+			},
+		}
+	}
+	testLine := func(source *mock.Source, processor *mock.Processor, sink *mock.Sink, expected error) func(*testing.T) {
+		return func(t *testing.T) {
+			t.Helper()
+			l := setupLine(source, processor, sink)
+			mutations := mutability.Mutations{}.
+				Put(processor.MockMutation()).
+				Put(sink.MockMutation())
+			var err error
+			for err == nil {
+				err = l.Step(mutations)
+				mutations = nil
+			}
+			assertEqual(t, "error", errors.Is(err, expected), true)
+			assertEqual(t, "flush", errors.Is(l.Flush(context.Background()), sink.ErrorOnFlush), true)
+			if expected == io.EOF {
+				assertEqual(t, "sink samples", sink.Samples, source.Limit+processor.Tail)
+			}
+			assertEqual(t, "processor mutated", processor.Mutated, true)
+			assertEqual(t, "sink mutated", sink.Mutated, true)
+			assertEqual(t, "source flushed", source.Flushed, true)
+			assertEqual(t, "processor flushed", processor.Flushed, true)
+			assertEqual(t, "sink flushed", sink.Flushed, true)
+		}
+	}
+	t.Run("ok", testLine(
+		&mock.Source{
+			Channels: channels,
+			Limit:    10 * bufferSize,
+		},
+		&mock.Processor{
+			Mutator: mock.Mutator{
+				Mutability: mutability.Mutable(),
+			},
+			Tail: 2 * bufferSize,
+		},
+		&mock.Sink{
+			Mutator: mock.Mutator{
+				Mutability: mutability.Mutable(),
+			},
+		},
+		io.EOF,
+	))
+	t.Run("mutation error", testLine(
+		&mock.Source{
+			Channels: channels,
+			Limit:    bufferSize,
+		},
+		&mock.Processor{
+			Mutator: mock.Mutator{
+				Mutability: mutability.Mutable(),
+			},
+		},
+		&mock.Sink{
+			Mutator: mock.Mutator{
+				Mutability:      mutability.Mutable(),
+				ErrorOnMutation: testError,
+			},
+		},
+		testError,
+	))
+	t.Run("flush error", testLine(
+		&mock.Source{
+			Channels: channels,
+			Limit:    bufferSize,
+		},
+		&mock.Processor{
+			Mutator: mock.Mutator{
+				Mutability: mutability.Mutable(),
+			},
+		},
+		&mock.Sink{
+			Mutator: mock.Mutator{
+				Mutability: mutability.Mutable(),
+			},
+			Flusher: mock.Flusher{
+				ErrorOnFlush: testError,
+			},
+		},
+		io.EOF,
+	))
+}
+
 func assertEqual(t *testing.T, name string, result, expected interface{}) {
 	t.Helper()
 	if !reflect.DeepEqual(expected, result) {
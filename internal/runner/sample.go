@@ -0,0 +1,155 @@
+package runner
+
+import (
+	"fmt"
+
+	"pipelined.dev/signal"
+)
+
+// SampleType is the type of samples in the signal buffers.
+type SampleType uint8
+
+// Supported sample types. Int32 buffers have 32 bits depth.
+const (
+	Float64 SampleType = iota
+	Float32
+	Int32
+)
+
+// Floating returns true if samples of the type are floating-point
+// values.
+func (t SampleType) Floating() bool {
+	return t != Int32
+}
+
+// String returns the name of the sample type.
+func (t SampleType) String() string {
+	switch t {
+	case Float64:
+		return "float64"
+	case Float32:
+		return "float32"
+	case Int32:
+		return "int32"
+	}
+	return fmt.Sprintf("sample type %d", uint8(t))
+}
+
+// get returns the buffer of the sample type from the pool.
+func (t SampleType) get(p *signal.PoolAllocator) signal.Signal {
+	switch t {
+	case Float32:
+		return p.GetFloat32()
+	case Int32:
+		return p.GetInt32(signal.BitDepth32)
+	}
+	return p.GetFloat64()
+}
+
+// FloatingSource adapts floating-point source function.
+func FloatingSource(fn func(signal.Floating) (int, error)) func(signal.Signal) (int, error) {
+	if fn == nil {
+		return nil
+	}
+	return func(out signal.Signal) (int, error) {
+		return fn(out.(signal.Floating))
+	}
+}
+
+// SignedSource adapts fixed-point source function.
+func SignedSource(fn func(signal.Signed) (int, error)) func(signal.Signal) (int, error) {
+	if fn == nil {
+		return nil
+	}
+	return func(out signal.Signal) (int, error) {
+		return fn(out.(signal.Signed))
+	}
+}
+
+// FloatingProcessor adapts floating-point processor function.
+func FloatingProcessor(fn func(in, out signal.Floating) error) func(in, out signal.Signal) error {
+	if fn == nil {
+		return nil
+	}
+	return func(in, out signal.Signal) error {
+		return fn(in.(signal.Floating), out.(signal.Floating))
+	}
+}
+
+// SignedProcessor adapts fixed-point processor function.
+func SignedProcessor(fn func(in, out signal.Signed) error) func(in, out signal.Signal) error {
+	if fn == nil {
+		return nil
+	}
+	return func(in, out signal.Signal) error {
+		return fn(in.(signal.Signed), out.(signal.Signed))
+	}
+}
+
+// FloatingSink adapts floating-point sink function.
+func FloatingSink(fn func(signal.Floating) error) func(signal.Signal) error {
+	if fn == nil {
+		return nil
+	}
+	return func(in signal.Signal) error {
+		return fn(in.(signal.Floating))
+	}
+}
+
+// SignedSink adapts fixed-point sink function.
+func SignedSink(fn func(signal.Signed) error) func(signal.Signal) error {
+	if fn == nil {
+		return nil
+	}
+	return func(in signal.Signal) error {
+		return fn(in.(signal.Signed))
+	}
+}
+
+// Copy copies samples from source to destination buffer, converting
+// them if sample types are different. Returns the number of samples
+// per channel copied.
+func Copy(src, dst signal.Signal) int {
+	switch src := src.(type) {
+	case signal.Floating:
+		switch dst := dst.(type) {
+		case signal.Floating:
+			return signal.FloatingAsFloating(src, dst)
+		case signal.Signed:
+			return signal.FloatingAsSigned(src, dst)
+		}
+	case signal.Signed:
+		switch dst := dst.(type) {
+		case signal.Floating:
+			return signal.SignedAsFloating(src, dst)
+		case signal.Signed:
+			return signal.SignedAsSigned(src, dst)
+		}
+	}
+	panic(fmt.Sprintf("unsupported signal types %T and %T", src, dst))
+}
+
+// slice returns the slice of the buffer.
+func slice(s signal.Signal, start, end int) signal.Signal {
+	switch s := s.(type) {
+	case signal.Floating:
+		return s.Slice(start, end)
+	case signal.Signed:
+		return s.Slice(start, end)
+	}
+	panic(fmt.Sprintf("unsupported signal type %T", s))
+}
+
+// silence sets all samples of the buffer to zero.
+func silence(s signal.Signal) {
+	switch s := s.(type) {
+	case signal.Floating:
+		for i := 0; i < s.Len(); i++ {
+			s.SetSample(i, 0)
+		}
+	case signal.Signed:
+		for i := 0; i < s.Len(); i++ {
+			s.SetSample(i, 0)
+		}
+	}
+}
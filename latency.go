@@ -0,0 +1,112 @@
+package pipe
+
+import (
+	"fmt"
+	"math"
+
+	"pipelined.dev/signal"
+
+	"pipelined.dev/pipe/internal/runner"
+)
+
+// Latency returns the total latency of line processors in samples of
+// the signal consumed by the sink.
+func (l *Line) Latency() int {
+	output := l.sampleRate()
+	var latency float64
+	for _, s := range l.stages {
+		latency += float64(s.Latency) * float64(output) / float64(s.Properties.SampleRate)
+	}
+	return int(math.Round(latency))
+}
+
+// sampleRate returns the sample rate of the signal consumed by the sink.
+func (l *Line) sampleRate() signal.Frequency {
+	return l.stages[len(l.stages)-1].Properties.SampleRate
+}
+
+// AlignLatency inserts compensating delays in front of the sinks, so all
+// lines have the same latency. It's needed when parallel lines are
+// joined, for example by a mixer, and must stay phase-aligned. Sinks of
+// all lines must have the same sample rate. It must be called before
+// lines are started.
+func AlignLatency(lines ...*Line) error {
+	if len(lines) == 0 {
+		return nil
+	}
+	var (
+		sampleRate = lines[0].sampleRate()
+		max        int
+	)
+	for _, l := range lines {
+		if sr := l.sampleRate(); sr != sampleRate {
+			return fmt.Errorf("error aligning latency: different sample rates %v and %v", sampleRate, sr)
+		}
+		if latency := l.Latency(); latency > max {
+			max = latency
+		}
+	}
+	for _, l := range lines {
+		if delay := max - l.Latency(); delay > 0 {
+			l.delay(delay)
+		}
+	}
+	return nil
+}
+
+// delay inserts the delay processor in front of the sink. Delayed
+// samples are flushed by the processor tail.
+func (l *Line) delay(samples int) {
+	sink := l.stages[len(l.stages)-1]
+	d := delayLine{
+		buffer: make([]float64, samples*sink.Properties.Channels),
+	}
+	pool := signal.GetPoolAllocator(sink.Properties.Channels, sink.BufferSize, sink.BufferSize)
+	l.processors = append(l.processors, runner.Processor{
+		InPool:  pool,
+		OutPool: pool,
+		InType:  sink.SampleType.runner(),
+		OutType: sink.SampleType.runner(),
+		Fn:      d.process,
+		Tail:    samples,
+	})
+	delay := sink
+	delay.Name = fmt.Sprintf("delay %d samples", samples)
+	delay.Latency = samples
+	l.stages = append(l.stages[:len(l.stages)-1], delay, sink)
+}
+
+// delayLine delays the interleaved signal by the length of its buffer.
+// Fixed-point samples are stored as float64 values, which is lossless
+// for 32 bits depth.
+type delayLine struct {
+	buffer []float64
+	pos    int
+}
+
+func (d *delayLine) process(in, out signal.Signal) error {
+	switch in := in.(type) {
+	case signal.Floating:
+		out := out.(signal.Floating)
+		for i := 0; i < in.Len(); i++ {
+			out.SetSample(i, d.next(in.Sample(i)))
+		}
+	case signal.Signed:
+		out := out.(signal.Signed)
+		for i := 0; i < in.Len(); i++ {
+			out.SetSample(i, int64(d.next(float64(in.Sample(i)))))
+		}
+	}
+	return nil
+}
+
+// next puts the sample into the delay line and returns the delayed one.
+func (d *delayLine) next(v float64) float64 {
+	delayed := d.buffer[d.pos]
+	d.buffer[d.pos] = v
+	d.pos++
+	if d.pos == len(d.buffer) {
+		d.pos = 0
+	}
+	return delayed
+}
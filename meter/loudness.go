@@ -0,0 +1,271 @@
+package meter
+
+import (
+	"math"
+	"sync/atomic"
+
+	"pipelined.dev/signal"
+
+	"pipelined.dev/pipe"
+)
+
+// Loudness is the loudness reading in LUFS. Momentary loudness is
+// measured over 400 milliseconds, short-term over 3 seconds and
+// integrated over the whole signal with gating. Silence has negative
+// infinite loudness.
+type Loudness struct {
+	Momentary  float64
+	ShortTerm  float64
+	Integrated float64
+}
+
+// Gating parameters and histogram of block loudness.
+const (
+	absoluteGate  = -70.0
+	relativeGate  = -10.0
+	histogramMax  = 10.0
+	histogramStep = 0.01
+	histogramBins = int((histogramMax - absoluteGate) / histogramStep)
+)
+
+// Number of 100 ms sub-blocks in the measurement windows.
+const (
+	momentaryBlocks = 4
+	shortTermBlocks = 30
+)
+
+// LUFS measures the loudness as defined in ITU-R BS.1770. Signal is
+// K-weighted and channels are summed with weights defined by the layout
+// of the signal: surround channels have 1.41 weight and LFE channel is
+// excluded. Integrated loudness is gated with absolute -70 LUFS and
+// relative -10 LU gates.
+type LUFS struct {
+	// momentary, short-term and integrated loudness stored as float64
+	// bits, so they can be read while the runner updates them. It's the
+	// first field to keep 64-bit alignment of atomics on 32-bit
+	// platforms.
+	reading [3]uint64
+
+	Readings chan<- Loudness
+}
+
+// Snapshot returns the last reading.
+func (m *LUFS) Snapshot() Loudness {
+	return Loudness{
+		Momentary:  math.Float64frombits(atomic.LoadUint64(&m.reading[0])),
+		ShortTerm:  math.Float64frombits(atomic.LoadUint64(&m.reading[1])),
+		Integrated: math.Float64frombits(atomic.LoadUint64(&m.reading[2])),
+	}
+}
+
+// Sink returns allocator of the loudness meter sink.
+func (m *LUFS) Sink() pipe.SinkAllocatorFunc {
+	return sink(m.analyzer)
+}
+
+// Processor returns allocator of the pass-through loudness meter.
+func (m *LUFS) Processor() pipe.ProcessorAllocatorFunc {
+	return processor(m.analyzer)
+}
+
+func (m *LUFS) publish(reading Loudness) {
+	atomic.StoreUint64(&m.reading[0], math.Float64bits(reading.Momentary))
+	atomic.StoreUint64(&m.reading[1], math.Float64bits(reading.ShortTerm))
+	atomic.StoreUint64(&m.reading[2], math.Float64bits(reading.Integrated))
+	if m.Readings != nil {
+		select {
+		case m.Readings <- reading:
+		default:
+		}
+	}
+}
+
+func (m *LUFS) analyzer(props pipe.SignalProperties) (func(signal.Floating), error) {
+	silence := math.Float64bits(math.Inf(-1))
+	for i := range m.reading {
+		atomic.StoreUint64(&m.reading[i], silence)
+	}
+
+	weights := channelWeights(props.Layout, props.Channels)
+	filters := make([][2]biquad, props.Channels)
+	for c := range filters {
+		filters[c] = kWeighting(props.SampleRate)
+	}
+	subBlock := int(math.Round(float64(props.SampleRate) / 10))
+	if subBlock < 1 {
+		subBlock = 1
+	}
+	var (
+		// mean square of the current sub-block.
+		sum float64
+		n   int
+		// ring of sub-block mean squares.
+		blocks [shortTermBlocks]float64
+		pos    int
+		filled int
+		g      gating
+	)
+	g.counts = make([]int, histogramBins)
+	g.sums = make([]float64, histogramBins)
+	return func(in signal.Floating) {
+		for i := 0; i < in.Length(); i++ {
+			for c := range filters {
+				v := in.Sample(in.BufferIndex(c, i))
+				v = filters[c][1].process(filters[c][0].process(v))
+				sum += weights[c] * v * v
+			}
+			if n++; n < subBlock {
+				continue
+			}
+			blocks[pos] = sum / float64(subBlock)
+			pos = (pos + 1) % shortTermBlocks
+			if filled < shortTermBlocks {
+				filled++
+			}
+			sum, n = 0, 0
+			if filled >= momentaryBlocks {
+				g.add(mean(blocks[:], pos, momentaryBlocks))
+			}
+		}
+		if filled == 0 {
+			return
+		}
+		momentary := filled
+		if momentary > momentaryBlocks {
+			momentary = momentaryBlocks
+		}
+		m.publish(Loudness{
+			Momentary:  loudness(mean(blocks[:], pos, momentary)),
+			ShortTerm:  loudness(mean(blocks[:], pos, filled)),
+			Integrated: g.integrated(),
+		})
+	}, nil
+}
+
+// mean returns the mean of n values of the ring that precede pos.
+func mean(ring []float64, pos, n int) float64 {
+	var s float64
+	for i := 1; i <= n; i++ {
+		s += ring[(pos-i+len(ring))%len(ring)]
+	}
+	return s / float64(n)
+}
+
+// loudness converts mean square into LUFS.
+func loudness(meanSquare float64) float64 {
+	return -0.691 + 10*math.Log10(meanSquare)
+}
+
+// gating holds the histogram of 400 ms blocks that passed the absolute
+// gate. Each bin has the number of blocks and the sum of their mean
+// squares.
+type gating struct {
+	counts []int
+	sums   []float64
+	count  int
+	sum    float64
+}
+
+func (g *gating) add(meanSquare float64) {
+	l := loudness(meanSquare)
+	if !(l > absoluteGate) {
+		return
+	}
+	bin := int((l - absoluteGate) / histogramStep)
+	if bin >= histogramBins {
+		bin = histogramBins - 1
+	}
+	g.counts[bin]++
+	g.sums[bin] += meanSquare
+	g.count++
+	g.sum += meanSquare
+}
+
+// integrated returns the gated loudness.
+func (g *gating) integrated() float64 {
+	if g.count == 0 {
+		return math.Inf(-1)
+	}
+	threshold := loudness(g.sum/float64(g.count)) + relativeGate
+	var (
+		sum   float64
+		count int
+	)
+	for bin, c := range g.counts {
+		if c == 0 || absoluteGate+(float64(bin)+0.5)*histogramStep <= threshold {
+			continue
+		}
+		sum += g.sums[bin]
+		count += c
+	}
+	if count == 0 {
+		return math.Inf(-1)
+	}
+	return loudness(sum / float64(count))
+}
+
+// channelWeights returns the weights of channels for the layout.
+func channelWeights(layout pipe.ChannelLayout, channels int) []float64 {
+	weights := make([]float64, channels)
+	for c := range weights {
+		weights[c] = 1
+	}
+	switch layout {
+	case pipe.QuadLayout:
+		// L, R and surround channels.
+		weights[2], weights[3] = 1.41, 1.41
+	case pipe.Surround51Layout, pipe.Surround71Layout:
+		// L, R, C, LFE and surround channels.
+		weights[3] = 0
+		for c := 4; c < channels; c++ {
+			weights[c] = 1.41
+		}
+	}
+	return weights
+}
+
+// biquad is the second-order filter in transposed direct form II.
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	z1, z2             float64
+}
+
+func (f *biquad) process(x float64) float64 {
+	y := f.b0*x + f.z1
+	f.z1 = f.b1*x - f.a1*y + f.z2
+	f.z2 = f.b2*x - f.a2*y
+	return y
+}
+
+// kWeighting returns the K-weighting filter for the sample rate: the
+// high shelf stage followed by the high-pass stage. Coefficients are
+// derived from the analog prototype, so they match BS.1770 values at
+// 48 kHz.
+func kWeighting(sampleRate signal.Frequency) [2]biquad {
+	fs := float64(sampleRate)
+
+	f0, gain, q := 1681.974450955533, 3.999843853973347, 0.7071752369554196
+	k := math.Tan(math.Pi * f0 / fs)
+	vh := math.Pow(10, gain/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1 + k/q + k*k
+	shelf := biquad{
+		b0: (vh + vb*k/q + k*k) / a0,
+		b1: 2 * (k*k - vh) / a0,
+		b2: (vh - vb*k/q + k*k) / a0,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+
+	f0, q = 38.13547087602444, 0.5003270373238773
+	k = math.Tan(math.Pi * f0 / fs)
+	a0 = 1 + k/q + k*k
+	highPass := biquad{
+		b0: 1,
+		b1: -2,
+		b2: 1,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+	return [2]biquad{shelf, highPass}
+}
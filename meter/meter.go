@@ -0,0 +1,262 @@
+// Package meter provides metering components: sample peak, RMS, true
+// peak and loudness meters.
+//
+// Each meter can be used as a sink or as a pass-through processor.
+// Readings are updated after each buffer. They can be observed with
+// Snapshot method from any goroutine or received from the Readings
+// channel. Readings are sent without blocking, so they are dropped if
+// the channel is not ready. Meters don't lock or allocate while the pipe
+// runs, so levels received from the channel are reused: a reading is
+// valid until the next one is received.
+package meter
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+
+	"pipelined.dev/signal"
+
+	"pipelined.dev/pipe"
+)
+
+// Levels are the levels of each channel in dB relative to the full
+// scale. Current is the level of the last buffer, Max is the maximum
+// level since the start. Silence has negative infinite level.
+type Levels struct {
+	Current []float64
+	Max     []float64
+}
+
+// analyzer returns function that measures the signal with provided
+// properties.
+type analyzer func(pipe.SignalProperties) (func(signal.Floating), error)
+
+// sink returns allocator of the sink that measures the signal.
+func sink(fn analyzer) pipe.SinkAllocatorFunc {
+	return func(bufferSize int, props pipe.SignalProperties) (pipe.Sink, error) {
+		analyze, err := fn(props)
+		if err != nil {
+			return pipe.Sink{}, err
+		}
+		return pipe.Sink{
+			SinkFunc: func(in signal.Floating) error {
+				analyze(in)
+				return nil
+			},
+		}, nil
+	}
+}
+
+// processor returns allocator of the processor that measures the signal
+// and passes it through.
+func processor(fn analyzer) pipe.ProcessorAllocatorFunc {
+	return func(bufferSize int, props pipe.SignalProperties) (pipe.Processor, pipe.SignalProperties, error) {
+		analyze, err := fn(props)
+		if err != nil {
+			return pipe.Processor{}, pipe.SignalProperties{}, err
+		}
+		return pipe.Processor{
+			ProcessFunc: func(in, out signal.Floating) error {
+				for i := 0; i < in.Len(); i++ {
+					out.SetSample(i, in.Sample(i))
+				}
+				analyze(in)
+				return nil
+			},
+		}, props, nil
+	}
+}
+
+// levels holds the last levels reading of the allocated meter.
+type levels struct {
+	state atomic.Value
+}
+
+// levelsState holds the readings of a single allocation. Levels are
+// stored as float64 bits, so they can be read while the runner updates
+// them. Readings sent into the channel are rotated, the reading that is
+// updated is never the one held by the receiver or buffered in the
+// channel.
+type levelsState struct {
+	current  []uint64
+	max      []uint64
+	readings []Levels
+	next     int
+}
+
+// Snapshot returns the last reading.
+func (l *levels) Snapshot() Levels {
+	s, ok := l.state.Load().(*levelsState)
+	if !ok {
+		return Levels{}
+	}
+	reading := Levels{
+		Current: make([]float64, len(s.current)),
+		Max:     make([]float64, len(s.max)),
+	}
+	for c := range s.current {
+		reading.Current[c] = math.Float64frombits(atomic.LoadUint64(&s.current[c]))
+		reading.Max[c] = math.Float64frombits(atomic.LoadUint64(&s.max[c]))
+	}
+	return reading
+}
+
+// reset initializes the reading for provided number of channels and
+// capacity of the readings channel.
+func (l *levels) reset(channels int, readings chan<- Levels) *levelsState {
+	s := levelsState{
+		current: make([]uint64, channels),
+		max:     make([]uint64, channels),
+	}
+	silence := math.Float64bits(math.Inf(-1))
+	for c := 0; c < channels; c++ {
+		s.current[c] = silence
+		s.max[c] = silence
+	}
+	if readings != nil {
+		s.readings = make([]Levels, cap(readings)+2)
+		for i := range s.readings {
+			s.readings[i] = Levels{
+				Current: make([]float64, channels),
+				Max:     make([]float64, channels),
+			}
+		}
+	}
+	l.state.Store(&s)
+	return &s
+}
+
+// publish updates the reading with linear amplitudes and sends it into
+// the channel if it's ready.
+func (s *levelsState) publish(readings chan<- Levels, amplitudes []float64) {
+	for c, v := range amplitudes {
+		db := decibels(v)
+		atomic.StoreUint64(&s.current[c], math.Float64bits(db))
+		if db > math.Float64frombits(s.max[c]) {
+			atomic.StoreUint64(&s.max[c], math.Float64bits(db))
+		}
+	}
+	if readings == nil {
+		return
+	}
+	reading := s.readings[s.next]
+	for c := range s.current {
+		reading.Current[c] = math.Float64frombits(s.current[c])
+		reading.Max[c] = math.Float64frombits(s.max[c])
+	}
+	select {
+	case readings <- reading:
+		s.next = (s.next + 1) % len(s.readings)
+	default:
+	}
+}
+
+// decibels converts linear amplitude into decibels.
+func decibels(v float64) float64 {
+	return 20 * math.Log10(v)
+}
+
+// Peak measures the sample peak of each channel.
+type Peak struct {
+	levels
+	Readings chan<- Levels
+}
+
+// Sink returns allocator of the peak meter sink.
+func (m *Peak) Sink() pipe.SinkAllocatorFunc {
+	return sink(m.analyzer)
+}
+
+// Processor returns allocator of the pass-through peak meter.
+func (m *Peak) Processor() pipe.ProcessorAllocatorFunc {
+	return processor(m.analyzer)
+}
+
+func (m *Peak) analyzer(props pipe.SignalProperties) (func(signal.Floating), error) {
+	state := m.reset(props.Channels, m.Readings)
+	peaks := make([]float64, props.Channels)
+	return func(in signal.Floating) {
+		for c := range peaks {
+			peaks[c] = 0
+			for i := 0; i < in.Length(); i++ {
+				peaks[c] = math.Max(peaks[c], math.Abs(in.Sample(in.BufferIndex(c, i))))
+			}
+		}
+		state.publish(m.Readings, peaks)
+	}, nil
+}
+
+// defaultWindow is the RMS window if it's not set.
+const defaultWindow = 300 * time.Millisecond
+
+// RMS measures the root mean square level of each channel over sliding
+// Window. 300 milliseconds window is used if it's zero.
+type RMS struct {
+	levels
+	Readings chan<- Levels
+	Window   time.Duration
+}
+
+// Sink returns allocator of the RMS meter sink.
+func (m *RMS) Sink() pipe.SinkAllocatorFunc {
+	return sink(m.analyzer)
+}
+
+// Processor returns allocator of the pass-through RMS meter.
+func (m *RMS) Processor() pipe.ProcessorAllocatorFunc {
+	return processor(m.analyzer)
+}
+
+func (m *RMS) analyzer(props pipe.SignalProperties) (func(signal.Floating), error) {
+	state := m.reset(props.Channels, m.Readings)
+	window := m.Window
+	if window == 0 {
+		window = defaultWindow
+	}
+	length := props.SampleRate.Events(window)
+	if length < 1 {
+		length = 1
+	}
+	// ring buffers of squared values and their sums.
+	squares := make([][]float64, props.Channels)
+	for c := range squares {
+		squares[c] = make([]float64, length)
+	}
+	sums := make([]float64, props.Channels)
+	rms := make([]float64, props.Channels)
+	pos, filled := 0, 0
+	return func(in signal.Floating) {
+		for i := 0; i < in.Length(); i++ {
+			for c := range squares {
+				v := in.Sample(in.BufferIndex(c, i))
+				sums[c] += v*v - squares[c][pos]
+				squares[c][pos] = v * v
+			}
+			if filled < length {
+				filled++
+			}
+			if pos = (pos + 1) % length; pos == 0 {
+				// recalculate sums to avoid the drift of rounding errors.
+				for c := range sums {
+					sums[c] = sum(squares[c])
+				}
+			}
+		}
+		if filled == 0 {
+			return
+		}
+		for c := range rms {
+			rms[c] = math.Sqrt(math.Max(sums[c], 0) / float64(filled))
+		}
+		state.publish(m.Readings, rms)
+	}, nil
+}
+
+func sum(values []float64) float64 {
+	var s float64
+	for _, v := range values {
+		s += v
+	}
+	return s
+}
@@ -0,0 +1,213 @@
+package meter_test
+
+import (
+	"context"
+	"io"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"pipelined.dev/signal"
+
+	"pipelined.dev/pipe"
+	"pipelined.dev/pipe/generator"
+	"pipelined.dev/pipe/meter"
+	"pipelined.dev/pipe/mock"
+)
+
+const bufferSize = 512
+
+// run executes the line with provided source, processors and sink.
+func run(t *testing.T, source pipe.SourceAllocatorFunc, sink pipe.SinkAllocatorFunc, processors ...pipe.ProcessorAllocatorFunc) {
+	t.Helper()
+	line, err := pipe.Routing{
+		Source:     source,
+		Processors: processors,
+		Sink:       sink,
+	}.Line(bufferSize)
+	assertEqual(t, "line error", err, nil)
+	err = pipe.New(context.Background(), pipe.WithLines(line)).Wait()
+	assertEqual(t, "run error", err, nil)
+}
+
+func constant(channels int, value float64) pipe.SourceAllocatorFunc {
+	return (&mock.Source{
+		Limit:      10 * bufferSize,
+		Channels:   channels,
+		SampleRate: 44100,
+		Value:      value,
+	}).Source()
+}
+
+func TestPeak(t *testing.T) {
+	readings := make(chan meter.Levels, 1)
+	m := &meter.Peak{Readings: readings}
+	run(t, constant(2, -0.5), m.Sink())
+
+	reading := m.Snapshot()
+	assertNear(t, "current", reading.Current[1], -6.0206, 1e-3)
+	assertNear(t, "max", reading.Max[0], -6.0206, 1e-3)
+	// channel is not read during the run, so it has the first reading.
+	assertNear(t, "first reading", (<-readings).Current[0], -6.0206, 1e-3)
+
+	m = &meter.Peak{}
+	assertEqual(t, "empty", m.Snapshot(), meter.Levels{})
+	run(t, constant(1, 0), m.Sink())
+	assertEqual(t, "silence", math.IsInf(m.Snapshot().Current[0], -1), true)
+}
+
+func TestReadings(t *testing.T) {
+	readings := make(chan meter.Levels, 1)
+	m := &meter.Peak{Readings: readings}
+	sink, err := m.Sink()(bufferSize, pipe.SignalProperties{Channels: 1, SampleRate: 44100})
+	assertEqual(t, "error", err, nil)
+	in := signal.Allocator{Channels: 1, Length: bufferSize, Capacity: bufferSize}.Float64()
+	in.SetSample(0, 0.5)
+	assertEqual(t, "sink error", sink.SinkFunc(in), nil)
+	first := <-readings
+
+	// buffered and received readings are not changed.
+	in.SetSample(0, 0.25)
+	allocs := testing.AllocsPerRun(10, func() {
+		sink.SinkFunc(in)
+	})
+	assertEqual(t, "allocs", allocs, 0.0)
+	second := <-readings
+	assertNear(t, "first", first.Current[0], -6.0206, 1e-3)
+	assertNear(t, "second", second.Current[0], -12.0412, 1e-3)
+	assertNear(t, "max", second.Max[0], -6.0206, 1e-3)
+}
+
+func TestRMS(t *testing.T) {
+	m := &meter.RMS{Window: 100 * time.Millisecond}
+	run(t, (&generator.Generator{
+		Waveform:   generator.Sine,
+		Frequency:  1000,
+		Amplitude:  1,
+		Channels:   2,
+		SampleRate: 48000,
+		Length:     48000,
+	}).Source(), m.Sink())
+	assertNear(t, "sine", m.Snapshot().Current[0], -3.0103, 1e-2)
+	assertNear(t, "max", m.Snapshot().Max[1], -3.0103, 2e-2)
+}
+
+func TestTruePeak(t *testing.T) {
+	// sine at quarter of sample rate with 45 degrees phase has sample
+	// peaks at -3 dB and true peak at 0 dB.
+	source := func(bufferSize int) (pipe.Source, pipe.SignalProperties, error) {
+		var n int
+		return pipe.Source{
+			SourceFunc: func(out signal.Floating) (int, error) {
+				if n == 10*bufferSize {
+					return 0, io.EOF
+				}
+				for i := 0; i < out.Length(); i++ {
+					out.SetSample(i, math.Sin(math.Pi/2*float64(n)+math.Pi/4))
+					n++
+				}
+				return out.Length(), nil
+			},
+		}, pipe.SignalProperties{
+			SampleRate: 48000,
+			Channels:   1,
+		}, nil
+	}
+	truePeak := &meter.TruePeak{}
+	peak := &meter.Peak{}
+	run(t, source, truePeak.Sink(), peak.Processor())
+	assertNear(t, "sample peak", peak.Snapshot().Max[0], -3.0103, 1e-3)
+	assertNear(t, "true peak", truePeak.Snapshot().Max[0], 0, 0.1)
+}
+
+func TestLoudness(t *testing.T) {
+	testLoudness := func(amplitude float64, layout pipe.ChannelLayout, channels int, expected float64) func(*testing.T) {
+		return func(t *testing.T) {
+			t.Helper()
+			g := generator.Generator{
+				Waveform:   generator.Sine,
+				Frequency:  1000,
+				Amplitude:  amplitude,
+				Channels:   channels,
+				SampleRate: 48000,
+				Length:     48000 * 5,
+			}
+			source := func(bufferSize int) (pipe.Source, pipe.SignalProperties, error) {
+				s, props, err := g.Source()(bufferSize)
+				props.Layout = layout
+				return s, props, err
+			}
+			m := &meter.LUFS{}
+			run(t, source, m.Sink())
+			reading := m.Snapshot()
+			assertNear(t, "momentary", reading.Momentary, expected, 0.1)
+			assertNear(t, "short-term", reading.ShortTerm, expected, 0.1)
+			assertNear(t, "integrated", reading.Integrated, expected, 0.1)
+		}
+	}
+	t.Run("mono full scale", testLoudness(1, pipe.MonoLayout, 1, -3.01))
+	t.Run("mono -20 dBFS", testLoudness(0.1, pipe.MonoLayout, 1, -23.01))
+	t.Run("stereo", testLoudness(0.1, pipe.StereoLayout, 2, -20.0))
+	// LFE is excluded and surround channels are weighted.
+	t.Run("quad", testLoudness(0.1, pipe.QuadLayout, 4, -23.01+10*math.Log10(2+2*1.41)))
+	t.Run("5.1", testLoudness(0.1, pipe.Surround51Layout, 6, -23.01+10*math.Log10(3+2*1.41)))
+
+	t.Run("silence", func(t *testing.T) {
+		m := &meter.LUFS{}
+		run(t, constant(2, 0), m.Sink())
+		assertEqual(t, "integrated", math.IsInf(m.Snapshot().Integrated, -1), true)
+	})
+
+	t.Run("gating", func(t *testing.T) {
+		readings := make(chan meter.Loudness, 1)
+		m := &meter.LUFS{Readings: readings}
+		// tone is followed by silence, which is gated.
+		tone := &generator.Generator{
+			Waveform:   generator.Sine,
+			Frequency:  1000,
+			Amplitude:  0.1,
+			Channels:   1,
+			SampleRate: 48000,
+		}
+		line, err := pipe.Routing{
+			Source: tone.Source(),
+			Sink:   m.Sink(),
+		}.Line(bufferSize)
+		assertEqual(t, "line error", err, nil)
+		r := pipe.NewRenderer(context.Background(), line)
+		assertEqual(t, "render error", r.Render(48000*5/bufferSize), nil)
+		r.Push(tone.SetAmplitude(0))
+		assertEqual(t, "render error", r.Render(48000*5/bufferSize), nil)
+		// the first reading is published after the first 100 ms.
+		reading := <-readings
+		assertNear(t, "first reading", reading.Momentary, -23.01, 0.5)
+		reading = m.Snapshot()
+		assertEqual(t, "momentary", math.IsInf(reading.Momentary, -1), true)
+		// blocks with the end of the tone pass the relative gate.
+		assertNear(t, "integrated", reading.Integrated, -23.01, 0.2)
+	})
+}
+
+func TestProcessor(t *testing.T) {
+	sink := &mock.Sink{}
+	m := &meter.Peak{}
+	run(t, constant(2, 0.5), sink.Sink(), m.Processor())
+	assertEqual(t, "samples", sink.Counter.Samples, 10*bufferSize)
+	assertEqual(t, "value", sink.Values.Sample(sink.Values.Len()-1), 0.5)
+	assertNear(t, "peak", m.Snapshot().Current[0], -6.0206, 1e-3)
+}
+
+func assertNear(t *testing.T, name string, result, expected, delta float64) {
+	t.Helper()
+	if math.Abs(result-expected) > delta {
+		t.Fatalf("%v\nresult: \t%v\nexpected: \t%v", name, result, expected)
+	}
+}
+
+func assertEqual(t *testing.T, name string, result, expected interface{}) {
+	t.Helper()
+	if !reflect.DeepEqual(expected, result) {
+		t.Fatalf("%v\nresult: \t%T\t%+v \nexpected: \t%T\t%+v", name, result, result, expected, expected)
+	}
+}
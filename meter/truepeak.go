@@ -0,0 +1,90 @@
+package meter
+
+import (
+	"math"
+
+	"pipelined.dev/signal"
+
+	"pipelined.dev/pipe"
+	"pipelined.dev/pipe/internal/dsp"
+)
+
+// tapsPerPhase is the length of each phase of the interpolation filter.
+const tapsPerPhase = 12
+
+// TruePeak measures the true peak of each channel as defined in ITU-R
+// BS.1770. Signal is oversampled 4 times if sample rate is below 96 kHz
+// and 2 times if it's below 192 kHz, the peak of oversampled signal is
+// measured.
+type TruePeak struct {
+	levels
+	Readings chan<- Levels
+}
+
+// Sink returns allocator of the true peak meter sink.
+func (m *TruePeak) Sink() pipe.SinkAllocatorFunc {
+	return sink(m.analyzer)
+}
+
+// Processor returns allocator of the pass-through true peak meter.
+func (m *TruePeak) Processor() pipe.ProcessorAllocatorFunc {
+	return processor(m.analyzer)
+}
+
+func (m *TruePeak) analyzer(props pipe.SignalProperties) (func(signal.Floating), error) {
+	state := m.reset(props.Channels, m.Readings)
+	factor := 4
+	switch {
+	case props.SampleRate >= 192000:
+		factor = 1
+	case props.SampleRate >= 96000:
+		factor = 2
+	}
+	filter := interpolation(factor)
+	history := make([][tapsPerPhase]float64, props.Channels)
+	peaks := make([]float64, props.Channels)
+	return func(in signal.Floating) {
+		for c := range peaks {
+			peaks[c] = 0
+			h := &history[c]
+			for i := 0; i < in.Length(); i++ {
+				copy(h[1:], h[:tapsPerPhase-1])
+				h[0] = in.Sample(in.BufferIndex(c, i))
+				for phase := 0; phase < factor; phase++ {
+					var v float64
+					for j := range h {
+						v += filter[phase+factor*j] * h[j]
+					}
+					peaks[c] = math.Max(peaks[c], math.Abs(v))
+				}
+			}
+		}
+		state.publish(m.Readings, peaks)
+	}, nil
+}
+
+// interpolation returns coefficients of windowed-sinc interpolation
+// filter for provided oversampling factor. Coefficients of each phase
+// are interleaved.
+func interpolation(factor int) []float64 {
+	if factor == 1 {
+		filter := make([]float64, tapsPerPhase)
+		filter[0] = 1
+		return filter
+	}
+	n := tapsPerPhase * factor
+	filter := make([]float64, n)
+	center := float64(n-1) / 2
+	var sum float64
+	for i := range filter {
+		x := (float64(i) - center) / float64(factor)
+		// symmetric blackman window.
+		w := dsp.Cosine(0.42, 0.5, 0.08, float64(i)/float64(n-1))
+		filter[i] = dsp.Sinc(x) * w
+		sum += filter[i]
+	}
+	for i := range filter {
+		filter[i] *= float64(factor) / sum
+	}
+	return filter
+}
@@ -0,0 +1,213 @@
+package pipe
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"pipelined.dev/signal"
+)
+
+// ErrInvalidSample is returned by SampleCheck middleware when signal
+// contains NaN, infinite or denormal values.
+var ErrInvalidSample = errors.New("invalid sample")
+
+// Middleware wraps components at allocation time. Each function receives
+// the name of the line stage and the component, and returns the wrapped
+// component. Wrapped functions are executed in the runner goroutine.
+// Nil functions leave components untouched. See Use for details.
+type Middleware struct {
+	Source    func(name string, s Source) Source
+	Processor func(name string, p Processor) Processor
+	Sink      func(name string, s Sink) Sink
+}
+
+// with returns allocator that wraps the source with middlewares. The
+// first middleware is the outermost one.
+func (fn SourceAllocatorFunc) with(name string, middlewares []Middleware) SourceAllocatorFunc {
+	if len(middlewares) == 0 {
+		return fn
+	}
+	return func(bufferSize int) (Source, SignalProperties, error) {
+		source, props, err := fn(bufferSize)
+		if err != nil {
+			return Source{}, SignalProperties{}, err
+		}
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			if middlewares[i].Source != nil {
+				source = middlewares[i].Source(name, source)
+			}
+		}
+		return source, props, nil
+	}
+}
+
+// with returns allocator that wraps the processor with middlewares. The
+// first middleware is the outermost one.
+func (fn ProcessorAllocatorFunc) with(name string, middlewares []Middleware) ProcessorAllocatorFunc {
+	if len(middlewares) == 0 {
+		return fn
+	}
+	return func(bufferSize int, props SignalProperties) (Processor, SignalProperties, error) {
+		processor, props, err := fn(bufferSize, props)
+		if err != nil {
+			return Processor{}, SignalProperties{}, err
+		}
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			if middlewares[i].Processor != nil {
+				processor = middlewares[i].Processor(name, processor)
+			}
+		}
+		return processor, props, nil
+	}
+}
+
+// with returns allocator that wraps the sink with middlewares. The
+// first middleware is the outermost one.
+func (fn SinkAllocatorFunc) with(name string, middlewares []Middleware) SinkAllocatorFunc {
+	if len(middlewares) == 0 {
+		return fn
+	}
+	return func(bufferSize int, props SignalProperties) (Sink, error) {
+		sink, err := fn(bufferSize, props)
+		if err != nil {
+			return Sink{}, err
+		}
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			if middlewares[i].Sink != nil {
+				sink = middlewares[i].Sink(name, sink)
+			}
+		}
+		return sink, nil
+	}
+}
+
+// Timing returns middleware that measures the duration of each call of
+// source, processor and sink functions. Results are reported to the
+// provided function in the runner goroutine, so it should return fast.
+func Timing(report func(name string, d time.Duration)) Middleware {
+	return Middleware{
+		Source: func(name string, s Source) Source {
+			if fn := s.SourceFunc; fn != nil {
+				s.SourceFunc = func(out signal.Floating) (int, error) {
+					defer measure(name, report, time.Now())
+					return fn(out)
+				}
+			}
+			if fn := s.SignedSourceFunc; fn != nil {
+				s.SignedSourceFunc = func(out signal.Signed) (int, error) {
+					defer measure(name, report, time.Now())
+					return fn(out)
+				}
+			}
+			return s
+		},
+		Processor: func(name string, p Processor) Processor {
+			if fn := p.ProcessFunc; fn != nil {
+				p.ProcessFunc = func(in, out signal.Floating) error {
+					defer measure(name, report, time.Now())
+					return fn(in, out)
+				}
+			}
+			if fn := p.SignedProcessFunc; fn != nil {
+				p.SignedProcessFunc = func(in, out signal.Signed) error {
+					defer measure(name, report, time.Now())
+					return fn(in, out)
+				}
+			}
+			return p
+		},
+		Sink: func(name string, s Sink) Sink {
+			if fn := s.SinkFunc; fn != nil {
+				s.SinkFunc = func(in signal.Floating) error {
+					defer measure(name, report, time.Now())
+					return fn(in)
+				}
+			}
+			if fn := s.SignedSinkFunc; fn != nil {
+				s.SignedSinkFunc = func(in signal.Signed) error {
+					defer measure(name, report, time.Now())
+					return fn(in)
+				}
+			}
+			return s
+		},
+	}
+}
+
+// measure reports the time elapsed since start. It's meant to be
+// deferred, so the start is captured when the call is scheduled.
+func measure(name string, report func(string, time.Duration), start time.Time) {
+	report(name, time.Since(start))
+}
+
+// SampleCheck returns middleware that checks floating-point signal for
+// NaN, infinite and denormal values. Output of sources and processors
+// and input of sinks are checked. If invalid value is found, the
+// ErrInvalidSample is returned. Fixed-point signal is not checked.
+func SampleCheck() Middleware {
+	return Middleware{
+		Source: func(name string, s Source) Source {
+			if fn := s.SourceFunc; fn != nil {
+				s.SourceFunc = func(out signal.Floating) (int, error) {
+					n, err := fn(out)
+					if err != nil {
+						return n, err
+					}
+					return n, checkSamples(name, out, n*out.Channels())
+				}
+			}
+			return s
+		},
+		Processor: func(name string, p Processor) Processor {
+			if fn := p.ProcessFunc; fn != nil {
+				p.ProcessFunc = func(in, out signal.Floating) error {
+					if err := fn(in, out); err != nil {
+						return err
+					}
+					return checkSamples(name, out, out.Len())
+				}
+			}
+			return p
+		},
+		Sink: func(name string, s Sink) Sink {
+			if fn := s.SinkFunc; fn != nil {
+				s.SinkFunc = func(in signal.Floating) error {
+					if err := checkSamples(name, in, in.Len()); err != nil {
+						return err
+					}
+					return fn(in)
+				}
+			}
+			return s
+		},
+	}
+}
+
+// checkSamples returns error if the first n values of the buffer contain
+// NaN, infinite or denormal values.
+func checkSamples(name string, s signal.Floating, n int) error {
+	threshold := minNormal64
+	if _, ok := s.(*signal.Float32); ok {
+		threshold = minNormal32
+	}
+	for i := 0; i < n; i++ {
+		v := s.Sample(i)
+		switch {
+		case math.IsNaN(v):
+		case math.IsInf(v, 0):
+		case v != 0 && math.Abs(v) < threshold:
+		default:
+			continue
+		}
+		return fmt.Errorf("%s: %w %v at index %d", name, ErrInvalidSample, v, i)
+	}
+	return nil
+}
+
+// Smallest positive normal values of floating-point sample types.
+const (
+	minNormal32 = 0x1p-126
+	minNormal64 = 0x1p-1022
+)
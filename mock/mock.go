@@ -117,6 +117,7 @@ type Processor struct {
 	Mutator
 	Counter
 	Flusher
+	Tail        int
 	ErrorOnCall error
 	ErrorOnMake error
 }
@@ -134,6 +135,7 @@ func (m *Processor) Processor() pipe.ProcessorAllocatorFunc {
 				m.Counter.advance(signal.FloatingAsFloating(in, out))
 				return nil
 			},
+			Tail: m.Tail,
 		}, props, m.ErrorOnMake
 	}
 }
@@ -52,8 +52,11 @@ func (m Mutability) Immutable() bool {
 	return m == immutable
 }
 
-// Apply mutator function.
+// Apply mutator function. Zero mutation is a no-op.
 func (m Mutation) Apply() error {
+	if m.mutator == nil {
+		return nil
+	}
 	return m.mutator()
 }
 
@@ -0,0 +1,7 @@
+//go:build !race
+// +build !race
+
+package pipe_test
+
+// race is true if tests are executed with race detector.
+const race = false
@@ -1,10 +1,64 @@
 package pipe
 
-import "pipelined.dev/pipe/mutability"
+import (
+	"runtime"
+
+	"pipelined.dev/pipe/mutability"
+)
 
 // Option represents pipe constructor parameter.
 type Option func(*Pipe)
 
+// LineOption represents line constructor parameter.
+type LineOption func(*Line)
+
+// Fusion makes the line to execute all its processors in a single
+// goroutine. It removes the channel hop between processors and is
+// useful when line has many cheap processors.
+func Fusion() LineOption {
+	return func(l *Line) {
+		l.fused = true
+	}
+}
+
+// Samples sets the sample type of the line buffers. Components that
+// don't provide functions for this type receive float64 or int32
+// buffers and sample converters are inserted at the boundaries. See
+// SampleType for details.
+func Samples(t SampleType) LineOption {
+	return func(l *Line) {
+		l.sampleType = t
+	}
+}
+
+// Use wraps line components with provided middlewares. Middlewares are
+// applied in the provided order: the first one is the outermost. Use can
+// be provided multiple times, middlewares are appended.
+func Use(middlewares ...Middleware) LineOption {
+	return func(l *Line) {
+		l.middlewares = append(l.middlewares, middlewares...)
+	}
+}
+
+// WithFusion enables processors fusion for all lines of the pipe. See
+// Fusion for details.
+func WithFusion() Option {
+	return func(p *Pipe) {
+		p.execution.fusion = true
+	}
+}
+
+// WithRingTransport makes runners to exchange messages through lock-free
+// single-producer single-consumer ring buffers instead of channels. Slots
+// is the number of messages each ring can hold, it's rounded up to the
+// nearest power of two. Waiting runners spin for a while before they
+// park, which reduces scheduler wakeups for small buffers.
+func WithRingTransport(slots int) Option {
+	return func(p *Pipe) {
+		p.execution.ringSlots = slots
+	}
+}
+
 // WithLines provides lines for the pipe.
 func WithLines(lines ...*Line) Option {
 	return func(p *Pipe) {
@@ -25,3 +79,32 @@ func WithMutations(mutations ...mutability.Mutation) Option {
 		}
 	}
 }
+
+// WithScheduler makes pipe to execute lines on a pool of at most
+// workers goroutines instead of running each component in its own
+// goroutine. Workers are started on demand and exit when there are no
+// lines to serve. Lines are executed in pull order: each sink pulls a
+// buffer through processors from the source. Workers serve lines in
+// round-robin order, one buffer at a time. It reduces the number of
+// goroutines when pipe has many lines. If workers is less than one, the
+// number of CPUs is used.
+func WithScheduler(workers int) Option {
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+	return func(p *Pipe) {
+		p.execution.scheduler = &scheduler{workers: workers}
+	}
+}
+
+// Conversion enables automatic format conversion for the line. If
+// processor or sink doesn't accept the signal, converters are inserted
+// in front of it: sample rate converter and channel mixer. Provided
+// matrices are used for channel mixing, see ChannelMatrix for details.
+func Conversion(matrices ...ChannelMatrix) LineOption {
+	return func(l *Line) {
+		l.conversion = &conversion{
+			matrices: matrices,
+		}
+	}
+}
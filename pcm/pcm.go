@@ -0,0 +1,262 @@
+// Package pcm provides source and sink of raw interleaved PCM, read from
+// io.Reader and written to io.Writer. It allows to pipe the signal from
+// and to other tools through stdin and stdout.
+package pcm
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"pipelined.dev/signal"
+
+	"pipelined.dev/pipe"
+)
+
+// Encoding is the encoding of PCM samples.
+type Encoding uint8
+
+// Supported encodings. All of them are little-endian, U8 is unsigned
+// with 128 as zero value.
+const (
+	S16LE Encoding = iota
+	S24LE
+	S32LE
+	F32LE
+	F64LE
+	U8
+)
+
+var encodings = [...]string{
+	S16LE: "s16le",
+	S24LE: "s24le",
+	S32LE: "s32le",
+	F32LE: "f32le",
+	F64LE: "f64le",
+	U8:    "u8",
+}
+
+// String returns the name of the encoding.
+func (e Encoding) String() string {
+	if int(e) >= len(encodings) {
+		return fmt.Sprintf("encoding %d", int(e))
+	}
+	return encodings[e]
+}
+
+// ParseEncoding returns the encoding with provided name.
+func ParseEncoding(name string) (Encoding, error) {
+	for e, n := range encodings {
+		if n == name {
+			return Encoding(e), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown encoding %q", name)
+}
+
+// Size returns the size of a single sample in bytes.
+func (e Encoding) Size() int {
+	switch e {
+	case U8:
+		return 1
+	case S16LE:
+		return 2
+	case S24LE:
+		return 3
+	case S32LE, F32LE:
+		return 4
+	}
+	return 8
+}
+
+// floating returns true if encoding has floating-point samples.
+func (e Encoding) floating() bool {
+	return e == F32LE || e == F64LE
+}
+
+// bitDepth returns the bit depth of fixed-point encodings.
+func (e Encoding) bitDepth() signal.BitDepth {
+	switch e {
+	case U8:
+		return signal.BitDepth8
+	case S16LE:
+		return signal.BitDepth16
+	case S24LE:
+		return signal.BitDepth24
+	case S32LE:
+		return signal.BitDepth32
+	}
+	return 0
+}
+
+func (e Encoding) valid() error {
+	if int(e) >= len(encodings) {
+		return fmt.Errorf("unknown encoding %d", int(e))
+	}
+	return nil
+}
+
+// Source reads interleaved PCM from the Reader. Fixed-point samples are
+// scaled to 32 bits depth. Incomplete frame at the end of the stream is
+// discarded.
+type Source struct {
+	Reader     io.Reader
+	Encoding   Encoding
+	Channels   int
+	SampleRate signal.Frequency
+}
+
+// Source returns allocator of the PCM source.
+func (s *Source) Source() pipe.SourceAllocatorFunc {
+	return func(bufferSize int) (pipe.Source, pipe.SignalProperties, error) {
+		if err := s.Encoding.valid(); err != nil {
+			return pipe.Source{}, pipe.SignalProperties{}, err
+		}
+		if s.Channels <= 0 {
+			return pipe.Source{}, pipe.SignalProperties{}, fmt.Errorf("invalid number of channels %d", s.Channels)
+		}
+		frameSize := s.Encoding.Size() * s.Channels
+		buf := make([]byte, bufferSize*frameSize)
+		// read fills the buffer and returns the number of complete frames.
+		read := func(length int) (int, error) {
+			n, err := io.ReadFull(s.Reader, buf[:length*frameSize])
+			if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+				return 0, err
+			}
+			if n < frameSize {
+				return 0, io.EOF
+			}
+			return n / frameSize, nil
+		}
+
+		source := pipe.Source{}
+		if s.Encoding.floating() {
+			source.SourceFunc = func(out signal.Floating) (int, error) {
+				n, err := read(out.Length())
+				if err != nil {
+					return 0, err
+				}
+				for i := 0; i < n*s.Channels; i++ {
+					out.SetSample(i, decodeFloat(s.Encoding, buf[i*s.Encoding.Size():]))
+				}
+				return n, nil
+			}
+		} else {
+			source.SignedSourceFunc = func(out signal.Signed) (int, error) {
+				n, err := read(out.Length())
+				if err != nil {
+					return 0, err
+				}
+				for i := 0; i < n*s.Channels; i++ {
+					out.SetSample(i, decodeSigned(s.Encoding, buf[i*s.Encoding.Size():]))
+				}
+				return n, nil
+			}
+		}
+		return source, pipe.SignalProperties{
+			SampleRate: s.SampleRate,
+			Channels:   s.Channels,
+			BitDepth:   s.Encoding.bitDepth(),
+		}, nil
+	}
+}
+
+// Sink writes interleaved PCM into the Writer. Fixed-point samples are
+// rounded to the bit depth of the encoding. Writes are buffered and
+// flushed when the line is done.
+type Sink struct {
+	Writer   io.Writer
+	Encoding Encoding
+}
+
+// Sink returns allocator of the PCM sink.
+func (s *Sink) Sink() pipe.SinkAllocatorFunc {
+	return func(bufferSize int, props pipe.SignalProperties) (pipe.Sink, error) {
+		if err := s.Encoding.valid(); err != nil {
+			return pipe.Sink{}, err
+		}
+		size := s.Encoding.Size()
+		buf := make([]byte, bufferSize*props.Channels*size)
+		w := bufio.NewWriter(s.Writer)
+
+		sink := pipe.Sink{
+			FlushFunc: func(context.Context) error {
+				return w.Flush()
+			},
+		}
+		if s.Encoding.floating() {
+			sink.SinkFunc = func(in signal.Floating) error {
+				for i := 0; i < in.Len(); i++ {
+					encodeFloat(s.Encoding, buf[i*size:], in.Sample(i))
+				}
+				_, err := w.Write(buf[:in.Len()*size])
+				return err
+			}
+		} else {
+			sink.SignedSinkFunc = func(in signal.Signed) error {
+				// shift values to 32 bits depth.
+				shift := signal.BitDepth32 - in.BitDepth()
+				for i := 0; i < in.Len(); i++ {
+					encodeSigned(s.Encoding, buf[i*size:], in.Sample(i)<<shift)
+				}
+				_, err := w.Write(buf[:in.Len()*size])
+				return err
+			}
+		}
+		return sink, nil
+	}
+}
+
+// decodeSigned returns the sample scaled to 32 bits depth.
+func decodeSigned(e Encoding, b []byte) int64 {
+	switch e {
+	case U8:
+		return int64(int32(uint32(b[0]^0x80) << 24))
+	case S16LE:
+		return int64(int32(uint32(b[0])<<16 | uint32(b[1])<<24))
+	case S24LE:
+		return int64(int32(uint32(b[0])<<8 | uint32(b[1])<<16 | uint32(b[2])<<24))
+	}
+	return int64(int32(binary.LittleEndian.Uint32(b)))
+}
+
+// encodeSigned writes the sample of 32 bits depth. Value is rounded to
+// the bit depth of the encoding.
+func encodeSigned(e Encoding, b []byte, v int64) {
+	if shift := 32 - e.bitDepth(); shift > 0 {
+		v += 1 << (shift - 1)
+		if v > math.MaxInt32 {
+			v = math.MaxInt32
+		}
+	}
+	u := uint32(int32(v))
+	switch e {
+	case U8:
+		b[0] = byte(u>>24) ^ 0x80
+	case S16LE:
+		b[0], b[1] = byte(u>>16), byte(u>>24)
+	case S24LE:
+		b[0], b[1], b[2] = byte(u>>8), byte(u>>16), byte(u>>24)
+	default:
+		binary.LittleEndian.PutUint32(b, u)
+	}
+}
+
+func decodeFloat(e Encoding, b []byte) float64 {
+	if e == F32LE {
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+	}
+	return math.Float64frombits(binary.LittleEndian.Uint64(b))
+}
+
+func encodeFloat(e Encoding, b []byte, v float64) {
+	if e == F32LE {
+		binary.LittleEndian.PutUint32(b, math.Float32bits(float32(v)))
+		return
+	}
+	binary.LittleEndian.PutUint64(b, math.Float64bits(v))
+}
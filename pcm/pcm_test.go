@@ -0,0 +1,122 @@
+package pcm_test
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"reflect"
+	"testing"
+	"testing/iotest"
+
+	"pipelined.dev/pipe"
+	"pipelined.dev/pipe/mock"
+	"pipelined.dev/pipe/pcm"
+)
+
+const bufferSize = 512
+
+func TestRoundTrip(t *testing.T) {
+	testEncoding := func(encoding pcm.Encoding, value float64) func(*testing.T) {
+		return func(t *testing.T) {
+			t.Helper()
+			var buf bytes.Buffer
+			source := &mock.Source{
+				Limit:      1000,
+				Channels:   2,
+				SampleRate: 44100,
+				Value:      value,
+			}
+			run(t, source.Source(), (&pcm.Sink{Writer: &buf, Encoding: encoding}).Sink())
+			assertEqual(t, "bytes", buf.Len(), 1000*2*encoding.Size())
+
+			sink := &mock.Sink{}
+			run(t, (&pcm.Source{
+				Reader:     iotest.OneByteReader(&buf),
+				Encoding:   encoding,
+				Channels:   2,
+				SampleRate: 44100,
+			}).Source(), sink.Sink())
+			assertEqual(t, "samples", sink.Counter.Samples, 1000)
+			// fixed-point conversions are not exact.
+			for i := 0; i < sink.Values.Len(); i++ {
+				if v := sink.Values.Sample(i); math.Abs(v-value) > 1e-4 {
+					t.Fatalf("value %v at index %d, expected %v", v, i, value)
+				}
+			}
+		}
+	}
+	t.Run("u8", testEncoding(pcm.U8, 0.5))
+	t.Run("s16le", testEncoding(pcm.S16LE, 0.5))
+	t.Run("s24le", testEncoding(pcm.S24LE, -0.25))
+	t.Run("s32le", testEncoding(pcm.S32LE, 0.125))
+	t.Run("f32le", testEncoding(pcm.F32LE, -0.5))
+	t.Run("f64le", testEncoding(pcm.F64LE, 0.1))
+}
+
+func TestSink(t *testing.T) {
+	var buf bytes.Buffer
+	source := &mock.Source{
+		Limit:      2,
+		Channels:   1,
+		SampleRate: 44100,
+		Value:      -0.5,
+	}
+	run(t, source.Source(), (&pcm.Sink{Writer: &buf, Encoding: pcm.S16LE}).Sink())
+	assertEqual(t, "bytes", buf.Bytes(), []byte{0x00, 0xc0, 0x00, 0xc0})
+}
+
+func TestSourceIncompleteFrame(t *testing.T) {
+	// two complete stereo frames and a half of the third one.
+	data := []byte{
+		0x00, 0x40, 0x00, 0x40,
+		0x00, 0x40, 0x00, 0x40,
+		0x00, 0x40,
+	}
+	sink := &mock.Sink{}
+	run(t, (&pcm.Source{
+		Reader:     bytes.NewReader(data),
+		Encoding:   pcm.S16LE,
+		Channels:   2,
+		SampleRate: 44100,
+	}).Source(), sink.Sink())
+	assertEqual(t, "samples", sink.Counter.Samples, 2)
+	assertEqual(t, "value", math.Round(sink.Values.Sample(3)*1e4)/1e4, 0.5)
+}
+
+func TestErrors(t *testing.T) {
+	_, err := pipe.Routing{
+		Source: (&pcm.Source{Reader: &bytes.Buffer{}, Encoding: pcm.S16LE}).Source(),
+		Sink:   (&mock.Sink{}).Sink(),
+	}.Line(bufferSize)
+	assertEqual(t, "channels error", err != nil, true)
+
+	_, err = pipe.Routing{
+		Source: (&mock.Source{Channels: 1}).Source(),
+		Sink:   (&pcm.Sink{Writer: &bytes.Buffer{}, Encoding: 10}).Sink(),
+	}.Line(bufferSize)
+	assertEqual(t, "encoding error", err != nil, true)
+
+	_, err = pcm.ParseEncoding("u16le")
+	assertEqual(t, "parse error", err != nil, true)
+	e, err := pcm.ParseEncoding("f32le")
+	assertEqual(t, "parse error", err, nil)
+	assertEqual(t, "encoding", e, pcm.F32LE)
+}
+
+func run(t *testing.T, source pipe.SourceAllocatorFunc, sink pipe.SinkAllocatorFunc) {
+	t.Helper()
+	line, err := pipe.Routing{
+		Source: source,
+		Sink:   sink,
+	}.Line(bufferSize)
+	assertEqual(t, "line error", err, nil)
+	err = pipe.New(context.Background(), pipe.WithLines(line)).Wait()
+	assertEqual(t, "run error", err, nil)
+}
+
+func assertEqual(t *testing.T, name string, result, expected interface{}) {
+	t.Helper()
+	if !reflect.DeepEqual(expected, result) {
+		t.Fatalf("%v\nresult: \t%T\t%+v \nexpected: \t%T\t%+v", name, result, result, expected, expected)
+	}
+}
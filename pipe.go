@@ -12,9 +12,21 @@ import (
 
 type (
 	// SignalProperties contains information about input/output signal.
+	// Layout, BitDepth, Length and Metadata are optional, zero values
+	// mean that they are unknown. Processors forward them by default,
+	// see ProcessorAllocatorFunc for details.
 	SignalProperties struct {
 		SampleRate signal.Frequency
 		Channels   int
+		// Layout describes the position of the channels.
+		Layout ChannelLayout
+		// BitDepth is the original bit depth of the source.
+		BitDepth signal.BitDepth
+		// Length is the total length of the signal in samples per
+		// channel.
+		Length int
+		// Metadata contains stream metadata, like title or artist.
+		Metadata map[string]string
 	}
 
 	// SourceAllocatorFunc returns source for provided buffer size. It is
@@ -25,7 +37,9 @@ type (
 	// ProcessorAllocatorFunc returns processor for provided buffer size. It is
 	// responsible for pre-allocation of all necessary buffers and
 	// structures. Along with the processor, output signal properties are
-	// returned.
+	// returned. Optional output properties that are left zero are
+	// forwarded from the input, so processor needs to set only the ones
+	// it overrides.
 	ProcessorAllocatorFunc func(int, SignalProperties) (Processor, SignalProperties, error)
 
 	// SinkAllocatorFunc returns sink for provided buffer size. It is
@@ -39,16 +53,33 @@ type (
 	Source struct {
 		mutability.Mutability
 		SourceFunc
+		SignedSourceFunc
 		FlushFunc
 	}
 
 	// Processor is a mutator of signal data. Optinaly, mutability can be
 	// provided to handle mutations and flush hook to handle resource clean
-	// up.
+	// up. Latency is the delay in samples that processor adds to the
+	// output signal. Tail is the number of samples per channel that
+	// processor keeps producing after the end of input, like reverb or
+	// delay tail. After the source is done, processor receives silent
+	// input buffers until the tail is done. Length is only needed if
+	// processor changes the length of the signal, like sample rate
+	// converter. It returns the length of the output buffer for the
+	// input length and is called before each process function.
+	// BufferSize is required along with Length, it's the maximum length
+	// of output buffers and the buffer size of the following components.
 	Processor struct {
 		mutability.Mutability
 		ProcessFunc
+		SignedProcessFunc
 		FlushFunc
+		Latency    int
+		Tail       int
+		Length     func(int) int
+		BufferSize int
+		// nested are mutabilities of processors executed by this one.
+		nested []mutability.Mutability
 	}
 
 	// Sink is a destination of signal data. Optinaly, mutability can be
@@ -57,20 +88,36 @@ type (
 	Sink struct {
 		mutability.Mutability
 		SinkFunc
+		SignedSinkFunc
 		FlushFunc
 	}
 
 	// SourceFunc takes the output buffer and fills it with a signal data.
+	// It returns the number of samples per channel read. If it's less
+	// than the buffer size, the buffer is sliced to the read length.
 	// If no data is available, io.EOF should be returned.
 	SourceFunc func(out signal.Floating) (int, error)
 
 	// ProcessFunc takes the input buffer, applies processing logic and writes
-	// the result into output buffer.
+	// the result into output buffer. Output buffer has the same length
+	// as input one, unless processor defines Length. The length might be
+	// less than the buffer size, for example for the last buffer of the
+	// stream.
 	ProcessFunc func(in, out signal.Floating) error
 
 	// SinkFunc takes the input buffer and writes that to the underlying destination.
+	// The length of the buffer might be less than the buffer size.
 	SinkFunc func(in signal.Floating) error
 
+	// SignedSourceFunc is the SourceFunc for fixed-point buffers.
+	SignedSourceFunc func(out signal.Signed) (int, error)
+
+	// SignedProcessFunc is the ProcessFunc for fixed-point buffers.
+	SignedProcessFunc func(in, out signal.Signed) error
+
+	// SignedSinkFunc is the SinkFunc for fixed-point buffers.
+	SignedSinkFunc func(in signal.Signed) error
+
 	// FlushFunc provides a hook to flush all buffers for the component.
 	FlushFunc func(context.Context) error
 )
@@ -87,10 +134,32 @@ type (
 	// Line is a sequence of bound DSP components.
 	Line struct {
 		numChannels int
+		fused       bool
+		sampleType  SampleType
+		middlewares []Middleware
+		conversion  *conversion
 		mutators    chan mutability.Mutations
 		source      runner.Source
 		processors  []runner.Processor
 		sink        runner.Sink
+		stages      []Stage
+		// routing maps the index of the routing processor to the index
+		// of its runner, conversions are executed by runners too.
+		routing []int
+	}
+
+	// Stage describes a component of the bound line. Properties are
+	// the properties of the signal produced by the source or processor
+	// and consumed by the sink. BufferSize is the size of buffers
+	// received by the component. Latency is reported by processors in
+	// samples of the output signal. SampleType is the type of buffers
+	// produced by the source or processor and consumed by the sink.
+	Stage struct {
+		Name       string
+		BufferSize int
+		Properties SignalProperties
+		Latency    int
+		SampleType SampleType
 	}
 
 	// Pipe listeners the execution of multiple chained lines. Lines might be chained
@@ -102,6 +171,7 @@ type (
 		cancelFn   context.CancelFunc
 		merger     *merger
 		lines      []*Line
+		execution  execution
 		listeners  map[mutability.Mutability]chan mutability.Mutations
 		mutations  map[chan mutability.Mutations]mutability.Mutations
 		push       chan []mutability.Mutation
@@ -110,7 +180,8 @@ type (
 )
 
 // Lines is a helper function that allows to bind multiple routes with
-// using same buffer size.
+// using same buffer size. Lines are meant to be executed by the same
+// pipe, so their sinks must consume the signal of the same sample rate.
 func Lines(bufferSize int, routes ...Routing) ([]*Line, error) {
 	var lines []*Line
 	for i := range routes {
@@ -120,92 +191,215 @@ func Lines(bufferSize int, routes ...Routing) ([]*Line, error) {
 		}
 		lines = append(lines, l)
 	}
+	if err := checkSampleRates(lines...); err != nil {
+		return nil, fmt.Errorf("error routing: %w", err)
+	}
 	return lines, nil
 }
 
 // Line binds components. All allocators are executed and wrapped into
-// runners. If any of allocators failed, the error will be returned and
-// flush hooks won't be triggered.
-func (r Routing) Line(bufferSize int) (*Line, error) {
-	source, input, err := r.Source.runner(bufferSize)
-	if err != nil {
-		return nil, fmt.Errorf("error routing %w", err)
+// runners. Signal properties are validated between components. If any
+// of allocators failed, the error will be returned and flush hooks won't
+// be triggered.
+func (r Routing) Line(bufferSize int, options ...LineOption) (*Line, error) {
+	if r.Sink == nil {
+		return nil, fmt.Errorf("error routing: sink is not defined")
 	}
+	l := Line{
+		mutators: make(chan mutability.Mutations, 1),
+	}
+	for _, option := range options {
+		option(&l)
+	}
+	if err := r.bind(&l, bufferSize); err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
 
-	var (
-		processors []runner.Processor
-		processor  runner.Processor
-	)
-	for _, fn := range r.Processors {
-		processor, input, err = fn.runner(bufferSize, input)
+// bind executes allocators and validates signal properties between
+// components. If conversion is enabled for the line, converters are
+// inserted in front of components that don't accept the signal. Sink is
+// optional.
+func (r Routing) bind(l *Line, bufferSize int) error {
+	if err := l.conversion.validate(); err != nil {
+		return fmt.Errorf("error routing: %w", err)
+	}
+	source, stage, err := r.Source.with("source", l.middlewares).runner(bufferSize, l.sampleType)
+	if err != nil {
+		return fmt.Errorf("error routing %w", err)
+	}
+	if err := stage.Properties.validate(); err != nil {
+		return fmt.Errorf("error routing source: %w", err)
+	}
+	output := stage.Properties
+	stage.Name = "source"
+	l.source = source
+	l.stages = append(l.stages, stage)
+
+	for i, fn := range r.Processors {
+		name := fmt.Sprintf("processor %d", i)
+		fn = fn.with(name, l.middlewares)
+		processor, stage, err := fn.runner(bufferSize, output, l.sampleType)
+		if accepts, ok := l.unsupported(err); ok {
+			bufferSize, output = l.convert(bufferSize, output, accepts)
+			processor, stage, err = fn.runner(bufferSize, output, l.sampleType)
+		}
 		if err != nil {
-			return nil, fmt.Errorf("error routing %w", err)
+			return fmt.Errorf("error routing %w", err)
+		}
+		stage.Properties = output.forward(stage.Properties)
+		if err := stage.Properties.validate(); err != nil {
+			return fmt.Errorf("error routing processor %d: %w", i, err)
 		}
-		processors = append(processors, processor)
+		l.convertSamples(bufferSize, output, stage.SampleType)
+		output = stage.Properties
+		bufferSize = processor.OutPool.Length
+		stage.Name = name
+		l.routing = append(l.routing, len(l.processors))
+		l.processors = append(l.processors, processor)
+		l.stages = append(l.stages, stage)
 	}
 
-	sink, err := r.Sink.runner(bufferSize, input)
+	if r.Sink == nil {
+		return nil
+	}
+	sinkFn := r.Sink.with("sink", l.middlewares)
+	sink, stage, err := sinkFn.runner(bufferSize, output, l.sampleType)
+	if accepts, ok := l.unsupported(err); ok {
+		bufferSize, output = l.convert(bufferSize, output, accepts)
+		sink, stage, err = sinkFn.runner(bufferSize, output, l.sampleType)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("error routing: %w", err)
+		return fmt.Errorf("error routing: %w", err)
 	}
+	l.convertSamples(bufferSize, output, stage.SampleType)
+	stage.Name = "sink"
+	l.sink = sink
+	l.stages = append(l.stages, stage)
+	return nil
+}
 
-	return &Line{
-		mutators:   make(chan mutability.Mutations, 1),
-		source:     source,
-		processors: processors,
-		sink:       sink,
-	}, nil
+// Stages returns the description of line components in the execution
+// order.
+func (l *Line) Stages() []Stage {
+	return append([]Stage(nil), l.stages...)
 }
 
 func (l *Line) listeners(listeners map[mutability.Mutability]chan mutability.Mutations) {
 	listeners[l.source.Mutability] = l.mutators
 	for i := range l.processors {
 		listeners[l.processors[i].Mutability] = l.mutators
+		if b := l.processors[i].Bypass; b != nil {
+			listeners[b.Mutability] = l.mutators
+		}
+		for _, id := range l.processors[i].Nested {
+			listeners[id] = l.mutators
+		}
 	}
 	listeners[l.sink.Mutability] = l.mutators
 }
 
-func (fn SourceAllocatorFunc) runner(bufferSize int) (runner.Source, SignalProperties, error) {
+// runner returns synchronous runner of the line.
+func (l *Line) runner() runner.Line {
+	return runner.Line{
+		Source:     l.source,
+		Processors: l.processors,
+		Sink:       l.sink,
+	}
+}
+
+func (fn SourceAllocatorFunc) runner(bufferSize int, sampleType SampleType) (runner.Source, Stage, error) {
 	source, output, err := fn(bufferSize)
 	if err != nil {
-		return runner.Source{}, SignalProperties{}, fmt.Errorf("source: %w", err)
+		return runner.Source{}, Stage{}, fmt.Errorf("source: %w", err)
 	}
-	return runner.Source{
+	sampleType = sampleType.choose(source.SourceFunc != nil, source.SignedSourceFunc != nil)
+	r := runner.Source{
 		Mutability: source.Mutability,
 		OutPool:    signal.GetPoolAllocator(output.Channels, bufferSize, bufferSize),
-		Fn:         source.SourceFunc,
+		OutType:    sampleType.runner(),
+		Fn:         runner.FloatingSource(source.SourceFunc),
 		Flush:      runner.Flush(source.FlushFunc),
-	}, output, nil
+	}
+	if !sampleType.runner().Floating() {
+		r.Fn = runner.SignedSource(source.SignedSourceFunc)
+	}
+	return r, Stage{
+		BufferSize: bufferSize,
+		Properties: output,
+		SampleType: sampleType,
+	}, nil
 }
 
-func (fn ProcessorAllocatorFunc) runner(bufferSize int, input SignalProperties) (runner.Processor, SignalProperties, error) {
+func (fn ProcessorAllocatorFunc) runner(bufferSize int, input SignalProperties, sampleType SampleType) (runner.Processor, Stage, error) {
 	processor, output, err := fn(bufferSize, input)
 	if err != nil {
-		return runner.Processor{}, SignalProperties{}, fmt.Errorf("processor: %w", err)
+		return runner.Processor{}, Stage{}, fmt.Errorf("processor: %w", err)
+	}
+	if processor.Latency < 0 {
+		return runner.Processor{}, Stage{}, fmt.Errorf("processor: negative latency %d", processor.Latency)
 	}
-	return runner.Processor{
+	if processor.Tail < 0 {
+		return runner.Processor{}, Stage{}, fmt.Errorf("processor: negative tail %d", processor.Tail)
+	}
+	outSize := bufferSize
+	if processor.Length != nil {
+		if processor.BufferSize < 1 {
+			return runner.Processor{}, Stage{}, fmt.Errorf("processor: invalid buffer size %d", processor.BufferSize)
+		}
+		outSize = processor.BufferSize
+	}
+	sampleType = sampleType.choose(processor.ProcessFunc != nil, processor.SignedProcessFunc != nil)
+	r := runner.Processor{
 		Mutability: processor.Mutability,
+		Nested:     processor.nested,
 		InPool:     signal.GetPoolAllocator(input.Channels, bufferSize, bufferSize),
-		OutPool:    signal.GetPoolAllocator(output.Channels, bufferSize, bufferSize),
-		Fn:         processor.ProcessFunc,
+		OutPool:    signal.GetPoolAllocator(output.Channels, outSize, outSize),
+		InType:     sampleType.runner(),
+		OutType:    sampleType.runner(),
+		Fn:         runner.FloatingProcessor(processor.ProcessFunc),
 		Flush:      runner.Flush(processor.FlushFunc),
-	}, output, nil
+		Length:     processor.Length,
+		Tail:       processor.Tail,
+		Bypass:     &runner.Bypass{Mutability: mutability.Mutable()},
+	}
+	if !sampleType.runner().Floating() {
+		r.Fn = runner.SignedProcessor(processor.SignedProcessFunc)
+	}
+	return r, Stage{
+		BufferSize: bufferSize,
+		Properties: output,
+		Latency:    processor.Latency,
+		SampleType: sampleType,
+	}, nil
 }
 
-func (fn SinkAllocatorFunc) runner(bufferSize int, input SignalProperties) (runner.Sink, error) {
+func (fn SinkAllocatorFunc) runner(bufferSize int, input SignalProperties, sampleType SampleType) (runner.Sink, Stage, error) {
 	sink, err := fn(bufferSize, input)
 	if err != nil {
-		return runner.Sink{}, fmt.Errorf("sink: %w", err)
+		return runner.Sink{}, Stage{}, fmt.Errorf("sink: %w", err)
 	}
-	return runner.Sink{
+	sampleType = sampleType.choose(sink.SinkFunc != nil, sink.SignedSinkFunc != nil)
+	r := runner.Sink{
 		Mutability: sink.Mutability,
 		InPool:     signal.GetPoolAllocator(input.Channels, bufferSize, bufferSize),
-		Fn:         sink.SinkFunc,
+		Fn:         runner.FloatingSink(sink.SinkFunc),
 		Flush:      runner.Flush(sink.FlushFunc),
+	}
+	if !sampleType.runner().Floating() {
+		r.Fn = runner.SignedSink(sink.SignedSinkFunc)
+	}
+	return r, Stage{
+		BufferSize: bufferSize,
+		Properties: input,
+		SampleType: sampleType,
 	}, nil
 }
 
-// New creates and starts new pipe.
+// New creates and starts new pipe. Lines of the pipe are chained, so
+// their sinks must consume the signal of the same sample rate, otherwise
+// the pipe isn't started and Wait returns the error.
 func New(ctx context.Context, options ...Option) *Pipe {
 	ctx, cancelFn := context.WithCancel(ctx)
 	p := Pipe{
@@ -227,9 +421,16 @@ func New(ctx context.Context, options ...Option) *Pipe {
 	if len(p.lines) == 0 {
 		panic("pipe without lines")
 	}
+	// lines are chained, so they must have the same sample rate.
+	if err := checkSampleRates(p.lines...); err != nil {
+		cancelFn()
+		p.errors <- fmt.Errorf("pipe error: %w", err)
+		close(p.errors)
+		return &p
+	}
 	// push cached mutators at the start
 	push(p.mutations)
-	p.merger.merge(start(p.ctx, p.lines)...)
+	p.merger.merge(start(p.ctx, p.execution, p.lines)...)
 	go p.merger.wait()
 	go func() {
 		defer close(p.errors)
@@ -282,31 +483,60 @@ func (p *Pipe) interrupt(err error) {
 	p.errors <- fmt.Errorf("pipe error: %w", err)
 }
 
+// execution defines how line runners are executed.
+type execution struct {
+	// fusion enables processors fusion for all lines.
+	fusion bool
+	// ringSlots is the number of slots in ring links. If zero, channels
+	// are used.
+	ringSlots int
+	// scheduler executes lines on a pool of workers. If nil, each
+	// component runs in its own goroutine.
+	scheduler *scheduler
+}
+
+// link returns a new link between two runners.
+func (e execution) link() runner.Link {
+	if e.ringSlots > 0 {
+		return runner.NewRing(e.ringSlots)
+	}
+	return make(runner.Chan, 1)
+}
+
 // start starts the execution of pipe.
-func start(ctx context.Context, lines []*Line) []<-chan error {
+func start(ctx context.Context, e execution, lines []*Line) []<-chan error {
 	// start all runners
 	// error channel for each component
 	errChans := make([]<-chan error, 0, 2*len(lines))
 	for i := range lines {
-		errChans = append(errChans, lines[i].start(ctx)...)
+		errChans = append(errChans, lines[i].start(ctx, e)...)
 	}
 	return errChans
 }
 
-func (l *Line) start(ctx context.Context) []<-chan error {
+func (l *Line) start(ctx context.Context, e execution) []<-chan error {
+	if e.scheduler != nil {
+		return []<-chan error{e.scheduler.schedule(ctx, l)}
+	}
 	errChans := make([]<-chan error, 0, 2+len(l.processors))
 	// start source
-	out, errs := l.source.Run(ctx, l.mutators)
-	errChans = append(errChans, errs)
+	out := e.link()
+	errChans = append(errChans, l.source.Start(ctx, l.mutators, out))
 
 	// start chained processesing
-	for _, proc := range l.processors {
-		out, errs = proc.Run(ctx, out)
-		errChans = append(errChans, errs)
+	if (e.fusion || l.fused) && len(l.processors) > 0 {
+		in := out
+		out = e.link()
+		errChans = append(errChans, runner.Fused(l.processors).Start(ctx, in, out))
+	} else {
+		for _, proc := range l.processors {
+			in := out
+			out = e.link()
+			errChans = append(errChans, proc.Start(ctx, in, out))
+		}
 	}
 
-	errs = l.sink.Run(ctx, out)
-	errChans = append(errChans, errs)
+	errChans = append(errChans, l.sink.Start(ctx, out))
 	return errChans
 }
 
@@ -319,8 +549,11 @@ func (p *Pipe) Push(mutations ...mutability.Mutation) {
 // AddLine adds the line to the pipe.
 func (p *Pipe) AddLine(l *Line) mutability.Mutation {
 	return p.mutability.Mutate(func() error {
+		if err := checkSampleRates(p.lines[0], l); err != nil {
+			return fmt.Errorf("error adding line: %w", err)
+		}
 		addLine(p, l)
-		p.merger.merge(l.start(p.ctx)...)
+		p.merger.merge(l.start(p.ctx, p.execution)...)
 		return nil
 	})
 }
@@ -3,8 +3,15 @@ package pipe_test
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
 	"reflect"
 	"testing"
+	"time"
+
+	"pipelined.dev/signal"
 
 	"pipelined.dev/pipe"
 	"pipelined.dev/pipe/mock"
@@ -15,8 +22,9 @@ const bufferSize = 512
 
 func TestSimplePipe(t *testing.T) {
 	source := &mock.Source{
-		Limit:    862 * bufferSize,
-		Channels: 2,
+		Limit:      862 * bufferSize,
+		Channels:   2,
+		SampleRate: 44100,
 	}
 
 	proc1 := &mock.Processor{}
@@ -43,8 +51,9 @@ func TestReset(t *testing.T) {
 		Mutator: mock.Mutator{
 			Mutability: mutability.Mutable(),
 		},
-		Limit:    862 * bufferSize,
-		Channels: 2,
+		Limit:      862 * bufferSize,
+		Channels:   2,
+		SampleRate: 44100,
 	}
 	sink := &mock.Sink{Discard: true}
 
@@ -80,15 +89,17 @@ func TestAddLine(t *testing.T) {
 	lines, err := pipe.Lines(bufferSize,
 		pipe.Routing{
 			Source: (&mock.Source{
-				Limit:    862 * bufferSize,
-				Channels: 2,
+				Limit:      862 * bufferSize,
+				Channels:   2,
+				SampleRate: 44100,
 			}).Source(),
 			Sink: sink1.Sink(),
 		},
 		pipe.Routing{
 			Source: (&mock.Source{
-				Limit:    862 * bufferSize,
-				Channels: 2,
+				Limit:      862 * bufferSize,
+				Channels:   2,
+				SampleRate: 44100,
 			}).Source(),
 			Sink: sink2.Sink(),
 		},
@@ -116,8 +127,9 @@ func BenchmarkSingleLine(b *testing.B) {
 		Mutator: mock.Mutator{
 			Mutability: mutability.Mutable(),
 		},
-		Limit:    862 * bufferSize,
-		Channels: 2,
+		Limit:      862 * bufferSize,
+		Channels:   2,
+		SampleRate: 44100,
 	}
 	sink := &mock.Sink{Discard: true}
 	line, _ := pipe.Routing{
@@ -139,6 +151,1024 @@ func BenchmarkSingleLine(b *testing.B) {
 	b.Logf("recieved messages: %d samples: %d", sink.Messages, sink.Samples)
 }
 
+// This benchmark runs the same line as BenchmarkSingleLine, but with
+// fused processors.
+func BenchmarkFusedLine(b *testing.B) {
+	source := &mock.Source{
+		Mutator: mock.Mutator{
+			Mutability: mutability.Mutable(),
+		},
+		Limit:      862 * bufferSize,
+		Channels:   2,
+		SampleRate: 44100,
+	}
+	sink := &mock.Sink{Discard: true}
+	line, _ := pipe.Routing{
+		Source: source.Source(),
+		Processors: pipe.Processors(
+			(&mock.Processor{}).Processor(),
+			(&mock.Processor{}).Processor(),
+		),
+		Sink: sink.Sink(),
+	}.Line(bufferSize, pipe.Fusion())
+	for i := 0; i < b.N; i++ {
+		p := pipe.New(
+			context.Background(),
+			pipe.WithLines(line),
+			pipe.WithMutations(source.Reset()),
+		)
+		_ = p.Wait()
+	}
+	b.Logf("recieved messages: %d samples: %d", sink.Messages, sink.Samples)
+}
+
+// This benchmark compares channel and ring transports for different
+// buffer sizes. Each run transfers 862*512 samples through the line of
+// 1 Source, 2 Processors and 1 Sink.
+func BenchmarkTransport(b *testing.B) {
+	benchmarkTransport := func(bufferSize int, options ...pipe.Option) func(*testing.B) {
+		return func(b *testing.B) {
+			source := &mock.Source{
+				Mutator: mock.Mutator{
+					Mutability: mutability.Mutable(),
+				},
+				Limit:      862 * 512,
+				Channels:   2,
+				SampleRate: 44100,
+			}
+			line, _ := pipe.Routing{
+				Source: source.Source(),
+				Processors: pipe.Processors(
+					(&mock.Processor{}).Processor(),
+					(&mock.Processor{}).Processor(),
+				),
+				Sink: (&mock.Sink{Discard: true}).Sink(),
+			}.Line(bufferSize)
+			for i := 0; i < b.N; i++ {
+				p := pipe.New(
+					context.Background(),
+					append(options,
+						pipe.WithLines(line),
+						pipe.WithMutations(source.Reset()),
+					)...,
+				)
+				_ = p.Wait()
+			}
+		}
+	}
+	for _, size := range []int{64, 256, 1024, 4096} {
+		b.Run(fmt.Sprintf("chan %d", size), benchmarkTransport(size))
+		b.Run(fmt.Sprintf("ring %d", size), benchmarkTransport(size, pipe.WithRingTransport(4)))
+	}
+}
+
+func TestRingTransport(t *testing.T) {
+	source := &mock.Source{
+		Limit:      862 * bufferSize,
+		Channels:   2,
+		SampleRate: 44100,
+	}
+	proc := &mock.Processor{}
+	sink := &mock.Sink{Discard: true}
+
+	line, err := pipe.Routing{
+		Source:     source.Source(),
+		Processors: pipe.Processors(proc.Processor()),
+		Sink:       sink.Sink(),
+	}.Line(bufferSize)
+	assertNil(t, "error", err)
+
+	p := pipe.New(
+		context.Background(),
+		pipe.WithLines(line),
+		pipe.WithRingTransport(2),
+	)
+	err = p.Wait()
+	assertNil(t, "error", err)
+	assertEqual(t, "processor samples", proc.Counter.Samples, 862*bufferSize)
+	assertEqual(t, "sink messages", sink.Counter.Messages, 862)
+	assertEqual(t, "sink samples", sink.Counter.Samples, 862*bufferSize)
+}
+
+func TestFusedLine(t *testing.T) {
+	source := &mock.Source{
+		Limit:      862 * bufferSize,
+		Channels:   2,
+		SampleRate: 44100,
+	}
+	proc1 := &mock.Processor{
+		Mutator: mock.Mutator{
+			Mutability: mutability.Mutable(),
+		},
+	}
+	proc2 := &mock.Processor{
+		Mutator: mock.Mutator{
+			Mutability: mutability.Mutable(),
+		},
+	}
+	sink := &mock.Sink{Discard: true}
+
+	line, err := pipe.Routing{
+		Source:     source.Source(),
+		Processors: pipe.Processors(proc1.Processor(), proc2.Processor()),
+		Sink:       sink.Sink(),
+	}.Line(bufferSize)
+	assertNil(t, "error", err)
+
+	p := pipe.New(
+		context.Background(),
+		pipe.WithLines(line),
+		pipe.WithFusion(),
+		pipe.WithMutations(proc1.MockMutation(), proc2.MockMutation()),
+	)
+	err = p.Wait()
+	assertNil(t, "error", err)
+
+	assertEqual(t, "proc1 mutated", proc1.Mutated, true)
+	assertEqual(t, "proc2 mutated", proc2.Mutated, true)
+	assertEqual(t, "proc1 flushed", proc1.Flushed, true)
+	assertEqual(t, "proc2 flushed", proc2.Flushed, true)
+	assertEqual(t, "proc2 samples", proc2.Counter.Samples, 862*bufferSize)
+	assertEqual(t, "sink messages", sink.Counter.Messages, 862)
+	assertEqual(t, "sink samples", sink.Counter.Samples, 862*bufferSize)
+}
+
+func TestPartialBuffer(t *testing.T) {
+	const limit = 3*bufferSize + 100
+	testPartialBuffer := func(run func(*pipe.Line) error) func(*testing.T) {
+		return func(t *testing.T) {
+			t.Helper()
+			proc1 := &mock.Processor{}
+			proc2 := &mock.Processor{}
+			sink := &mock.Sink{}
+			line, err := pipe.Routing{
+				Source: (&mock.Source{
+					Limit:      limit,
+					Channels:   2,
+					SampleRate: 44100,
+					Value:      0.5,
+				}).Source(),
+				Processors: pipe.Processors(proc1.Processor(), proc2.Processor()),
+				Sink:       sink.Sink(),
+			}.Line(bufferSize)
+			assertNil(t, "error", err)
+
+			err = run(line)
+			assertNil(t, "error", err)
+			assertEqual(t, "proc1 samples", proc1.Counter.Samples, limit)
+			assertEqual(t, "proc2 samples", proc2.Counter.Samples, limit)
+			assertEqual(t, "sink messages", sink.Counter.Messages, 4)
+			assertEqual(t, "sink samples", sink.Counter.Samples, limit)
+			assertEqual(t, "sink values", sink.Values.Length(), limit)
+			assertEqual(t, "last value", sink.Values.Sample(sink.Values.Len()-1), 0.5)
+		}
+	}
+	t.Run("async", testPartialBuffer(func(l *pipe.Line) error {
+		return pipe.New(context.Background(), pipe.WithLines(l)).Wait()
+	}))
+	t.Run("fused", testPartialBuffer(func(l *pipe.Line) error {
+		return pipe.New(context.Background(), pipe.WithLines(l), pipe.WithFusion()).Wait()
+	}))
+	t.Run("renderer", testPartialBuffer(func(l *pipe.Line) error {
+		if err := pipe.NewRenderer(context.Background(), l).Render(100); err != io.EOF {
+			return err
+		}
+		return nil
+	}))
+}
+
+func TestSampleType(t *testing.T) {
+	const (
+		limit = 3*bufferSize + 10
+		value = 1 << 30
+	)
+	signedSource := func(bufferSize int) (pipe.Source, pipe.SignalProperties, error) {
+		read := 0
+		return pipe.Source{
+			SignedSourceFunc: func(out signal.Signed) (int, error) {
+				if read == limit {
+					return 0, io.EOF
+				}
+				n := out.Length()
+				if limit-read < n {
+					n = limit - read
+				}
+				for i := 0; i < n*out.Channels(); i++ {
+					out.SetSample(i, value)
+				}
+				read += n
+				return n, nil
+			},
+		}, pipe.SignalProperties{
+			Channels:   2,
+			SampleRate: 44100,
+		}, nil
+	}
+	signedProcessor := func(bufferSize int, props pipe.SignalProperties) (pipe.Processor, pipe.SignalProperties, error) {
+		return pipe.Processor{
+			SignedProcessFunc: func(in, out signal.Signed) error {
+				for i := 0; i < in.Len(); i++ {
+					out.SetSample(i, in.Sample(i)+1)
+				}
+				return nil
+			},
+		}, props, nil
+	}
+	type result struct {
+		samples int
+		value   int64
+		types   map[string]bool
+	}
+	signedSink := func(r *result) pipe.SinkAllocatorFunc {
+		r.types = make(map[string]bool)
+		return func(bufferSize int, props pipe.SignalProperties) (pipe.Sink, error) {
+			return pipe.Sink{
+				SignedSinkFunc: func(in signal.Signed) error {
+					r.samples += in.Length()
+					r.value = in.Sample(in.Len() - 1)
+					r.types[fmt.Sprintf("%T", in)] = true
+					return nil
+				},
+			}, nil
+		}
+	}
+	testSampleType := func(route func(*result) pipe.Routing, sampleType pipe.SampleType, expectedStages []string, expectedValue int64) func(*testing.T) {
+		return func(t *testing.T) {
+			t.Helper()
+			var r result
+			line, err := route(&r).Line(bufferSize, pipe.Samples(sampleType))
+			assertNil(t, "error", err)
+
+			var stages []string
+			for _, s := range line.Stages() {
+				stages = append(stages, fmt.Sprintf("%s %v", s.Name, s.SampleType))
+			}
+			assertEqual(t, "stages", stages, expectedStages)
+
+			err = pipe.New(context.Background(), pipe.WithLines(line)).Wait()
+			assertNil(t, "error", err)
+			assertEqual(t, "samples", r.samples, limit)
+			assertEqual(t, "value", r.value, expectedValue)
+			assertEqual(t, "types", r.types, map[string]bool{"*signal.Int32": true})
+		}
+	}
+	t.Run("int32", testSampleType(
+		func(r *result) pipe.Routing {
+			return pipe.Routing{
+				Source:     signedSource,
+				Processors: pipe.Processors(signedProcessor, signedProcessor),
+				Sink:       signedSink(r),
+			}
+		},
+		pipe.Int32,
+		[]string{"source int32", "processor 0 int32", "processor 1 int32", "sink int32"},
+		value+2,
+	))
+	t.Run("floating processor", testSampleType(
+		func(r *result) pipe.Routing {
+			return pipe.Routing{
+				Source:     signedSource,
+				Processors: pipe.Processors((&mock.Processor{}).Processor(), signedProcessor),
+				Sink:       signedSink(r),
+			}
+		},
+		pipe.Int32,
+		[]string{
+			"source int32",
+			"sample converter int32->float64 float64",
+			"processor 0 float64",
+			"sample converter float64->int32 int32",
+			"processor 1 int32",
+			"sink int32",
+		},
+		value+1,
+	))
+	t.Run("float32", testSampleType(
+		func(r *result) pipe.Routing {
+			return pipe.Routing{
+				Source:     signedSource,
+				Processors: pipe.Processors((&mock.Processor{}).Processor()),
+				Sink:       signedSink(r),
+			}
+		},
+		pipe.Float32,
+		[]string{
+			"source int32",
+			"sample converter int32->float32 float32",
+			"processor 0 float32",
+			"sample converter float32->int32 int32",
+			"sink int32",
+		},
+		// float32 precision is lower than int32.
+		value-1,
+	))
+}
+
+// gain is a processor component that multiplies signal by its value.
+type gain struct {
+	mutability mutability.Mutability
+	value      float64
+	flushed    bool
+}
+
+func (g *gain) Process(in, out signal.Floating) error {
+	for i := 0; i < in.Len(); i++ {
+		out.SetSample(i, in.Sample(i)*g.value)
+	}
+	return nil
+}
+
+func (g *gain) Flush(context.Context) error {
+	g.flushed = true
+	return nil
+}
+
+func (g *gain) Mutability() mutability.Mutability {
+	return g.mutability
+}
+
+func (g *gain) setValue(value float64) mutability.Mutation {
+	return g.mutability.Mutate(func() error {
+		g.value = value
+		return nil
+	})
+}
+
+// peak is a sink component that tracks the peak value of the signal.
+type peak struct {
+	value int64
+}
+
+func (p *peak) Sink(in signal.Signed) error {
+	for i := 0; i < in.Len(); i++ {
+		if v := in.Sample(i); v > p.value {
+			p.value = v
+		}
+	}
+	return nil
+}
+
+func (p *peak) Flush(context.Context) error {
+	return nil
+}
+
+func (p *peak) Mutability() mutability.Mutability {
+	return mutability.Immutable()
+}
+
+func TestComponents(t *testing.T) {
+	g := &gain{
+		mutability: mutability.Mutable(),
+		value:      1,
+	}
+	p := &peak{}
+	line, err := pipe.Routing{
+		Source: (&mock.Source{
+			Limit:      10 * bufferSize,
+			Channels:   2,
+			SampleRate: 44100,
+			Value:      0.25,
+		}).Source(),
+		Processors: pipe.Processors(
+			pipe.ProcessorAllocator[signal.Floating](func(bufferSize int, props pipe.SignalProperties) (*gain, pipe.SignalProperties, error) {
+				return g, props, nil
+			}),
+		),
+		Sink: pipe.SinkAllocator[signal.Signed](func(bufferSize int, props pipe.SignalProperties) (*peak, error) {
+			return p, nil
+		}),
+	}.Line(bufferSize)
+	assertNil(t, "error", err)
+
+	err = pipe.New(
+		context.Background(),
+		pipe.WithLines(line),
+		pipe.WithMutations(g.setValue(2)),
+	).Wait()
+	assertNil(t, "error", err)
+	assertEqual(t, "flushed", g.flushed, true)
+	assertEqual(t, "peak", p.value, signal.BitDepth32.MaxSignedValue()/2)
+}
+
+func TestMiddleware(t *testing.T) {
+	var calls []string
+	trace := func(id string) pipe.Middleware {
+		return pipe.Middleware{
+			Processor: func(name string, p pipe.Processor) pipe.Processor {
+				fn := p.ProcessFunc
+				p.ProcessFunc = func(in, out signal.Floating) error {
+					calls = append(calls, id+" "+name)
+					return fn(in, out)
+				}
+				return p
+			},
+		}
+	}
+	timings := make(map[string]int)
+	line, err := pipe.Routing{
+		Source: (&mock.Source{
+			Limit:      2 * bufferSize,
+			Channels:   2,
+			SampleRate: 44100,
+		}).Source(),
+		Processors: pipe.Processors((&mock.Processor{}).Processor()),
+		Sink:       (&mock.Sink{}).Sink(),
+	}.Line(bufferSize,
+		pipe.Use(trace("outer"), trace("inner")),
+		pipe.Use(pipe.Timing(func(name string, d time.Duration) {
+			timings[name]++
+		})),
+	)
+	assertNil(t, "error", err)
+
+	err = pipe.NewRenderer(context.Background(), line).Render(10)
+	assertEqual(t, "error", err, io.EOF)
+	assertEqual(t, "calls", calls, []string{
+		"outer processor 0", "inner processor 0",
+		"outer processor 0", "inner processor 0",
+	})
+	assertEqual(t, "timings", timings, map[string]int{
+		"source":      3, // last call returns io.EOF.
+		"processor 0": 2,
+		"sink":        2,
+	})
+}
+
+func TestSampleCheck(t *testing.T) {
+	testSampleCheck := func(sampleType pipe.SampleType, value float64, expected error) func(*testing.T) {
+		return func(t *testing.T) {
+			t.Helper()
+			line, err := pipe.Routing{
+				Source: (&mock.Source{
+					Limit:      bufferSize,
+					Channels:   2,
+					SampleRate: 44100,
+					Value:      value,
+				}).Source(),
+				Sink: (&mock.Sink{}).Sink(),
+			}.Line(bufferSize, pipe.Samples(sampleType), pipe.Use(pipe.SampleCheck()))
+			assertNil(t, "error", err)
+
+			err = pipe.New(context.Background(), pipe.WithLines(line)).Wait()
+			assertEqual(t, "error", errors.Is(err, expected), true)
+		}
+	}
+	t.Run("ok", testSampleCheck(pipe.Float64, 0.5, nil))
+	t.Run("nan", testSampleCheck(pipe.Float64, math.NaN(), pipe.ErrInvalidSample))
+	t.Run("inf", testSampleCheck(pipe.Float64, math.Inf(-1), pipe.ErrInvalidSample))
+	t.Run("denormal", testSampleCheck(pipe.Float64, math.SmallestNonzeroFloat64, pipe.ErrInvalidSample))
+	t.Run("float64 normal", testSampleCheck(pipe.Float64, 1e-40, nil))
+	t.Run("float32 denormal", testSampleCheck(pipe.Float32, 1e-40, pipe.ErrInvalidSample))
+}
+
+func TestBypass(t *testing.T) {
+	sink := &mock.Sink{}
+	line, err := pipe.Routing{
+		Source: (&mock.Source{
+			Limit:      10 * bufferSize,
+			Channels:   2,
+			SampleRate: 44100,
+			Value:      0.5,
+		}).Source(),
+		Processors: pipe.Processors(
+			pipe.ProcessorAllocator[signal.Floating](func(bufferSize int, props pipe.SignalProperties) (*gain, pipe.SignalProperties, error) {
+				return &gain{value: 2}, props, nil
+			}),
+		),
+		Sink: sink.Sink(),
+	}.Line(bufferSize)
+	assertNil(t, "error", err)
+
+	// value returns the sample of the first channel.
+	value := func(buffer, idx int) float64 {
+		return sink.Values.Sample(sink.Values.BufferIndex(0, buffer*bufferSize+idx))
+	}
+	// bypass returns the bypass mutation of the first processor.
+	bypass := func(enabled, crossfade bool) mutability.Mutation {
+		m, err := line.Bypass(0, enabled, crossfade)
+		assertNil(t, "bypass error", err)
+		return m
+	}
+	r := pipe.NewRenderer(context.Background(), line)
+	assertNil(t, "error", r.Next())
+	assertEqual(t, "processed", value(0, 0), 1.0)
+
+	r.Push(bypass(true, false))
+	assertNil(t, "error", r.Next())
+	assertEqual(t, "bypassed", value(1, 0), 0.5)
+
+	r.Push(bypass(false, true))
+	assertNil(t, "error", r.Next())
+	assertEqual(t, "fade in start", value(2, 0) < 0.51, true)
+	assertEqual(t, "fade in end", value(2, bufferSize-1), 1.0)
+
+	r.Push(bypass(true, true))
+	assertNil(t, "error", r.Next())
+	assertEqual(t, "fade out start", value(3, 0) > 0.99, true)
+	assertEqual(t, "fade out end", value(3, bufferSize-1), 0.5)
+
+	assertNil(t, "error", r.Next())
+	assertEqual(t, "bypassed", value(4, 0), 0.5)
+
+	_, err = line.Bypass(1, true, false)
+	assertEqual(t, "index error", err != nil, true)
+}
+
+func TestBypassConversion(t *testing.T) {
+	sink := &mock.Sink{}
+	line, err := pipe.Routing{
+		Source: (&mock.Source{
+			Limit:      10 * bufferSize,
+			Channels:   1,
+			SampleRate: 44100,
+			Value:      0.5,
+		}).Source(),
+		Processors: pipe.Processors(
+			pipe.ProcessorAllocator[signal.Floating](func(bufferSize int, props pipe.SignalProperties) (*gain, pipe.SignalProperties, error) {
+				return &gain{value: 2}, props, nil
+			}).Accept(pipe.Accepts{Channels: []int{2}}),
+		),
+		Sink: sink.Sink(),
+	}.Line(bufferSize, pipe.Conversion())
+	assertNil(t, "error", err)
+	assertEqual(t, "stages", len(line.Stages()), 4)
+
+	// bypass targets the processor, not the channel mixer before it.
+	bypass, err := line.Bypass(0, true, false)
+	assertNil(t, "bypass error", err)
+	r := pipe.NewRenderer(context.Background(), line)
+	assertNil(t, "error", r.Next())
+	r.Push(bypass)
+	assertNil(t, "error", r.Next())
+	processed := sink.Values.Sample(sink.Values.BufferIndex(1, 0))
+	bypassed := sink.Values.Sample(sink.Values.BufferIndex(1, bufferSize))
+	assertEqual(t, "processed", processed, 2*bypassed)
+	assertEqual(t, "bypassed", bypassed != 0, true)
+}
+
+func TestProcessorTail(t *testing.T) {
+	const limit = 10*bufferSize + 1
+	testTail := func(run func(*pipe.Line) error) func(*testing.T) {
+		return func(t *testing.T) {
+			t.Helper()
+			proc1 := &mock.Processor{Tail: bufferSize + 10}
+			proc2 := &mock.Processor{Tail: 20}
+			line, err := pipe.Routing{
+				Source: (&mock.Source{
+					Limit:      limit,
+					Channels:   2,
+					SampleRate: 44100,
+				}).Source(),
+				Processors: pipe.Processors(proc1.Processor(), proc2.Processor()),
+				Sink:       (&mock.Sink{Discard: true}).Sink(),
+			}.Line(bufferSize)
+			assertNil(t, "error", err)
+
+			err = run(line)
+			assertNil(t, "error", err)
+			assertEqual(t, "proc1 samples", proc1.Counter.Samples, limit+bufferSize+10)
+			assertEqual(t, "proc2 samples", proc2.Counter.Samples, limit+bufferSize+10+20)
+		}
+	}
+	t.Run("async", testTail(func(l *pipe.Line) error {
+		return pipe.New(context.Background(), pipe.WithLines(l)).Wait()
+	}))
+	t.Run("fused", testTail(func(l *pipe.Line) error {
+		return pipe.New(context.Background(), pipe.WithLines(l), pipe.WithFusion()).Wait()
+	}))
+	t.Run("renderer", testTail(func(l *pipe.Line) error {
+		if err := pipe.NewRenderer(context.Background(), l).Render(100); err != io.EOF {
+			return err
+		}
+		return nil
+	}))
+	t.Run("negative", func(t *testing.T) {
+		_, err := pipe.Routing{
+			Source: (&mock.Source{
+				Channels:   2,
+				SampleRate: 44100,
+			}).Source(),
+			Processors: pipe.Processors((&mock.Processor{Tail: -1}).Processor()),
+			Sink:       (&mock.Sink{}).Sink(),
+		}.Line(bufferSize)
+		assertEqual(t, "error", err != nil, true)
+	})
+}
+
+func TestScheduler(t *testing.T) {
+	const numLines = 20
+	var (
+		sources []*mock.Source
+		sinks   []*mock.Sink
+		routes  []pipe.Routing
+	)
+	for i := 0; i < numLines; i++ {
+		source := &mock.Source{
+			Mutator: mock.Mutator{
+				Mutability: mutability.Mutable(),
+			},
+			Limit:      (10*i + 1) * bufferSize,
+			Channels:   2,
+			SampleRate: 44100,
+		}
+		sink := &mock.Sink{Discard: true}
+		sources = append(sources, source)
+		sinks = append(sinks, sink)
+		routes = append(routes, pipe.Routing{
+			Source:     source.Source(),
+			Processors: pipe.Processors((&mock.Processor{}).Processor()),
+			Sink:       sink.Sink(),
+		})
+	}
+	lines, err := pipe.Lines(bufferSize, routes...)
+	assertNil(t, "error", err)
+
+	p := pipe.New(
+		context.Background(),
+		pipe.WithScheduler(4),
+		pipe.WithLines(lines[:numLines-1]...),
+		pipe.WithMutations(sources[0].MockMutation()),
+	)
+	p.Push(p.AddLine(lines[numLines-1]))
+	err = p.Wait()
+	assertNil(t, "error", err)
+
+	assertEqual(t, "mutated", sources[0].Mutated, true)
+	for i := range sinks {
+		assertEqual(t, "samples", sinks[i].Counter.Samples, sources[i].Limit)
+		assertEqual(t, "flushed", sinks[i].Flushed, true)
+	}
+}
+
+func TestSchedulerError(t *testing.T) {
+	errorTest := errors.New("test error")
+	sink := &mock.Sink{ErrorOnCall: errorTest}
+	lines, err := pipe.Lines(bufferSize,
+		pipe.Routing{
+			Source: (&mock.Source{
+				Limit:      862 * bufferSize,
+				Channels:   2,
+				SampleRate: 44100,
+			}).Source(),
+			Sink: sink.Sink(),
+		},
+		pipe.Routing{
+			Source: (&mock.Source{
+				Limit:      862 * bufferSize,
+				Channels:   2,
+				SampleRate: 44100,
+			}).Source(),
+			Sink: (&mock.Sink{Discard: true}).Sink(),
+		},
+	)
+	assertNil(t, "error", err)
+
+	p := pipe.New(
+		context.Background(),
+		pipe.WithScheduler(1),
+		pipe.WithLines(lines...),
+	)
+	err = p.Wait()
+	assertEqual(t, "error", errors.Is(err, errorTest), true)
+	assertEqual(t, "flushed", sink.Flushed, true)
+}
+
+// This benchmark runs 200 lines of 1 Source, 2 Processors and 1 Sink with
+// 10 buffers of 512 samples each.
+func BenchmarkManyLines(b *testing.B) {
+	benchmarkManyLines := func(options ...pipe.Option) func(*testing.B) {
+		return func(b *testing.B) {
+			var (
+				routes    []pipe.Routing
+				mutations []mutability.Mutation
+			)
+			for i := 0; i < 200; i++ {
+				source := &mock.Source{
+					Mutator: mock.Mutator{
+						Mutability: mutability.Mutable(),
+					},
+					Limit:      10 * bufferSize,
+					Channels:   2,
+					SampleRate: 44100,
+				}
+				mutations = append(mutations, source.Reset())
+				routes = append(routes, pipe.Routing{
+					Source: source.Source(),
+					Processors: pipe.Processors(
+						(&mock.Processor{}).Processor(),
+						(&mock.Processor{}).Processor(),
+					),
+					Sink: (&mock.Sink{Discard: true}).Sink(),
+				})
+			}
+			lines, _ := pipe.Lines(bufferSize, routes...)
+			for i := 0; i < b.N; i++ {
+				p := pipe.New(
+					context.Background(),
+					append(options,
+						pipe.WithLines(lines...),
+						pipe.WithMutations(mutations...),
+					)...,
+				)
+				_ = p.Wait()
+			}
+		}
+	}
+	b.Run("goroutines", benchmarkManyLines())
+	b.Run("scheduler", benchmarkManyLines(pipe.WithScheduler(0)))
+}
+
+func TestRenderer(t *testing.T) {
+	source := &mock.Source{
+		Mutator: mock.Mutator{
+			Mutability: mutability.Mutable(),
+		},
+		Limit:      10*bufferSize + 1,
+		Channels:   2,
+		SampleRate: 44100,
+		Value:      0.5,
+	}
+	proc := &mock.Processor{
+		Mutator: mock.Mutator{
+			Mutability: mutability.Mutable(),
+		},
+	}
+	sink := &mock.Sink{}
+	line, err := pipe.Routing{
+		Source:     source.Source(),
+		Processors: pipe.Processors(proc.Processor()),
+		Sink:       sink.Sink(),
+	}.Line(bufferSize)
+	assertNil(t, "error", err)
+
+	r := pipe.NewRenderer(context.Background(), line, source.MockMutation())
+	err = r.Render(5)
+	assertNil(t, "error", err)
+	assertEqual(t, "source mutated", source.Mutated, true)
+	assertEqual(t, "messages", sink.Counter.Messages, 5)
+	assertEqual(t, "value", sink.Counter.Values.Sample(0), 0.5)
+
+	r.Push(proc.MockMutation())
+	err = r.Next()
+	assertNil(t, "error", err)
+	assertEqual(t, "processor mutated", proc.Mutated, true)
+	assertEqual(t, "messages", sink.Counter.Messages, 6)
+
+	err = r.Render(10)
+	assertEqual(t, "error", err, io.EOF)
+	assertEqual(t, "messages", sink.Counter.Messages, 11)
+	assertEqual(t, "source flushed", source.Flushed, true)
+	assertEqual(t, "processor flushed", proc.Flushed, true)
+	assertEqual(t, "sink flushed", sink.Flushed, true)
+	assertEqual(t, "error after done", r.Next(), io.EOF)
+}
+
+func TestRendererError(t *testing.T) {
+	errorTest := errors.New("test error")
+	sink := &mock.Sink{
+		Flusher: mock.Flusher{
+			ErrorOnFlush: errorTest,
+		},
+	}
+	line, err := pipe.Routing{
+		Source: (&mock.Source{
+			Limit:      bufferSize,
+			Channels:   2,
+			SampleRate: 44100,
+		}).Source(),
+		Sink: sink.Sink(),
+	}.Line(bufferSize)
+	assertNil(t, "error", err)
+
+	r := pipe.NewRenderer(context.Background(), line)
+	err = r.Render(2)
+	assertEqual(t, "error", errors.Is(err, errorTest), true)
+
+	ctx, cancelFn := context.WithCancel(context.Background())
+	cancelFn()
+	r = pipe.NewRenderer(ctx, line)
+	err = r.Next()
+	assertEqual(t, "error", errors.Is(err, errorTest), true)
+}
+
+// driver mocks audio driver that calls callback on its own thread.
+func driver(interval time.Duration, channels int, callback func(signal.Floating) error) <-chan error {
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		out := signal.Allocator{
+			Channels: channels,
+			Length:   bufferSize,
+			Capacity: bufferSize,
+		}.Float64()
+		for range ticker.C {
+			if err := callback(out); err != nil {
+				errs <- err
+				return
+			}
+		}
+	}()
+	return errs
+}
+
+func TestCallbackLine(t *testing.T) {
+	source := &mock.Source{
+		Mutator: mock.Mutator{
+			Mutability: mutability.Mutable(),
+		},
+		Limit:      10*bufferSize + 1,
+		Channels:   2,
+		SampleRate: 44100,
+		Value:      0.5,
+	}
+	proc := &mock.Processor{
+		Mutator: mock.Mutator{
+			Mutability: mutability.Mutable(),
+		},
+	}
+	sink := &mock.Sink{}
+	line, err := pipe.Routing{
+		Source:     source.Source(),
+		Processors: pipe.Processors(proc.Processor()),
+		Sink:       sink.Sink(),
+	}.CallbackLine(bufferSize)
+	assertNil(t, "error", err)
+
+	errs := driver(time.Millisecond, 2, line.Process)
+	line.Push(source.MockMutation(), proc.MockMutation())
+	err = <-errs
+	assertEqual(t, "error", err, io.EOF)
+	assertEqual(t, "source flushed in callback", source.Flushed, false)
+	assertNil(t, "flush error", line.Flush(context.Background()))
+	assertEqual(t, "source mutated", source.Mutated, true)
+	assertEqual(t, "processor mutated", proc.Mutated, true)
+	assertEqual(t, "processor samples", proc.Counter.Samples, 10*bufferSize+1)
+	assertEqual(t, "sink value", sink.Counter.Values.Sample(0), 0.5)
+	assertEqual(t, "source flushed", source.Flushed, true)
+	assertEqual(t, "processor flushed", proc.Flushed, true)
+	assertEqual(t, "sink flushed", sink.Flushed, true)
+}
+
+func TestCallbackLineOutput(t *testing.T) {
+	line, err := pipe.Routing{
+		Source: (&mock.Source{
+			Limit:      bufferSize + 1,
+			Channels:   1,
+			SampleRate: 44100,
+			Value:      0.5,
+		}).Source(),
+		Processors: pipe.Processors((&mock.Processor{}).Processor()),
+	}.CallbackLine(bufferSize)
+	assertNil(t, "error", err)
+
+	out := signal.Allocator{
+		Channels: 1,
+		Length:   bufferSize,
+		Capacity: bufferSize,
+	}.Float64()
+	err = line.Process(out)
+	assertNil(t, "error", err)
+	assertEqual(t, "first", out.Sample(0), 0.5)
+	assertEqual(t, "last", out.Sample(bufferSize-1), 0.5)
+
+	err = line.Process(out)
+	assertNil(t, "error", err)
+	assertEqual(t, "first", out.Sample(0), 0.5)
+	assertEqual(t, "silence", out.Sample(1), 0.0)
+
+	err = line.Process(out)
+	assertEqual(t, "error", err, io.EOF)
+	assertEqual(t, "silence", out.Sample(0), 0.0)
+
+	wrong := signal.Allocator{
+		Channels: 2,
+		Length:   bufferSize,
+		Capacity: bufferSize,
+	}.Float64()
+	line, _ = pipe.Routing{
+		Source: (&mock.Source{
+			Limit:      bufferSize,
+			Channels:   1,
+			SampleRate: 44100,
+		}).Source(),
+	}.CallbackLine(bufferSize)
+	err = line.Process(wrong)
+	assertEqual(t, "channels error", errors.Is(err, pipe.ErrCallbackChannels), true)
+}
+
+func TestCallbackLineBufferSize(t *testing.T) {
+	const limit = 3*bufferSize + 10
+	testBufferSize := func(hostSize int) func(*testing.T) {
+		return func(t *testing.T) {
+			t.Helper()
+			// ramp source, so lost samples and gaps are detected.
+			var n int
+			source := func(bufferSize int) (pipe.Source, pipe.SignalProperties, error) {
+				return pipe.Source{
+					SourceFunc: func(out signal.Floating) (int, error) {
+						if n == limit {
+							return 0, io.EOF
+						}
+						read := out.Length()
+						if left := limit - n; left < read {
+							read = left
+						}
+						for i := 0; i < read; i++ {
+							n++
+							out.SetSample(i, float64(n))
+						}
+						return read, nil
+					},
+				}, pipe.SignalProperties{
+					SampleRate: 44100,
+					Channels:   1,
+				}, nil
+			}
+			line, err := pipe.Routing{
+				Source: source,
+			}.CallbackLine(bufferSize)
+			assertNil(t, "error", err)
+
+			out := signal.Allocator{
+				Channels: 1,
+				Length:   hostSize,
+				Capacity: hostSize,
+			}.Float64()
+			var values []float64
+			for err == nil {
+				if err = line.Process(out); err == nil {
+					for i := 0; i < out.Length(); i++ {
+						values = append(values, out.Sample(i))
+					}
+				}
+			}
+			assertEqual(t, "error", err, io.EOF)
+			assertEqual(t, "length", len(values), (limit+hostSize-1)/hostSize*hostSize)
+			for i, v := range values {
+				expected := float64(i + 1)
+				if i >= limit {
+					expected = 0
+				}
+				assertEqual(t, "value", v, expected)
+			}
+		}
+	}
+	t.Run("smaller", testBufferSize(100))
+	t.Run("larger", testBufferSize(2*bufferSize+1))
+
+	t.Run("mutation error", func(t *testing.T) {
+		source := &mock.Source{
+			Mutator: mock.Mutator{
+				Mutability:      mutability.Mutable(),
+				ErrorOnMutation: errors.New("test"),
+			},
+			Channels:   1,
+			SampleRate: 44100,
+		}
+		proc := &mock.Processor{
+			Mutator: mock.Mutator{
+				Mutability: mutability.Mutable(),
+			},
+		}
+		line, err := pipe.Routing{
+			Source:     source.Source(),
+			Processors: pipe.Processors(proc.Processor()),
+		}.CallbackLine(bufferSize)
+		assertNil(t, "error", err)
+		line.Push(source.MockMutation(), proc.MockMutation())
+		err = line.Process(signal.Allocator{Channels: 1, Length: bufferSize, Capacity: bufferSize}.Float64())
+		assertEqual(t, "error", err != nil, true)
+		assertEqual(t, "processor mutated", proc.Mutated, true)
+	})
+}
+
+func TestCallbackLineAllocs(t *testing.T) {
+	if race {
+		t.Skip("pool allocates in race mode")
+	}
+	line, err := pipe.Routing{
+		Source: (&mock.Source{
+			Limit:      1000 * bufferSize,
+			Channels:   2,
+			SampleRate: 44100,
+		}).Source(),
+		Processors: pipe.Processors((&mock.Processor{}).Processor()),
+	}.CallbackLine(bufferSize)
+	assertNil(t, "error", err)
+
+	out := signal.Allocator{
+		Channels: 2,
+		Length:   bufferSize,
+		Capacity: bufferSize,
+	}.Float64()
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = line.Process(out)
+	})
+	assertEqual(t, "allocs", allocs, 0.0)
+}
+
 func TestLineBindingFail(t *testing.T) {
 	var (
 		errorBinding = errors.New("binding error")
@@ -164,7 +1194,10 @@ func TestLineBindingFail(t *testing.T) {
 	))
 	t.Run("processor", testBinding(
 		pipe.Routing{
-			Source: (&mock.Source{}).Source(),
+			Source: (&mock.Source{
+				Channels:   2,
+				SampleRate: 44100,
+			}).Source(),
 			Processors: pipe.Processors(
 				(&mock.Processor{
 					ErrorOnMake: errorBinding,
@@ -175,7 +1208,10 @@ func TestLineBindingFail(t *testing.T) {
 	))
 	t.Run("sink", testBinding(
 		pipe.Routing{
-			Source: (&mock.Source{}).Source(),
+			Source: (&mock.Source{
+				Channels:   2,
+				SampleRate: 44100,
+			}).Source(),
 			Processors: pipe.Processors(
 				(&mock.Processor{}).Processor(),
 			),
@@ -186,6 +1222,548 @@ func TestLineBindingFail(t *testing.T) {
 	))
 }
 
+func TestLineSignalProperties(t *testing.T) {
+	testProperties := func(r pipe.Routing, expected error) func(*testing.T) {
+		return func(t *testing.T) {
+			t.Helper()
+			_, err := r.Line(bufferSize)
+			assertEqual(t, "error", errors.Is(err, expected), true)
+		}
+	}
+	testUnsupported := func(r pipe.Routing, expected pipe.SignalProperties) func(*testing.T) {
+		return func(t *testing.T) {
+			t.Helper()
+			_, err := r.Line(bufferSize)
+			var unsupported *pipe.UnsupportedError
+			assertEqual(t, "unsupported", errors.As(err, &unsupported), true)
+			assertEqual(t, "properties", unsupported.Properties, expected)
+		}
+	}
+	accepts := pipe.Accepts{
+		SampleRates: []signal.Frequency{44100, 48000},
+		Channels:    []int{2},
+	}
+	t.Run("zero channels", testProperties(
+		pipe.Routing{
+			Source: (&mock.Source{SampleRate: 44100}).Source(),
+			Sink:   (&mock.Sink{}).Sink(),
+		},
+		pipe.ErrInvalidProperties,
+	))
+	t.Run("unknown sample rate", testProperties(
+		pipe.Routing{
+			Source: (&mock.Source{Channels: 2}).Source(),
+			Sink:   (&mock.Sink{}).Sink(),
+		},
+		pipe.ErrInvalidProperties,
+	))
+	t.Run("accepted", testProperties(
+		pipe.Routing{
+			Source: (&mock.Source{
+				Channels:   2,
+				SampleRate: 48000,
+			}).Source(),
+			Processors: pipe.Processors(
+				(&mock.Processor{}).Processor().Accept(accepts),
+			),
+			Sink: (&mock.Sink{}).Sink().Accept(accepts),
+		},
+		nil,
+	))
+	t.Run("processor channels", testUnsupported(
+		pipe.Routing{
+			Source: (&mock.Source{
+				Channels:   1,
+				SampleRate: 44100,
+			}).Source(),
+			Processors: pipe.Processors(
+				(&mock.Processor{}).Processor().Accept(accepts),
+			),
+			Sink: (&mock.Sink{}).Sink(),
+		},
+		pipe.SignalProperties{
+			Channels:   1,
+			SampleRate: 44100,
+		},
+	))
+	t.Run("sink sample rate", testUnsupported(
+		pipe.Routing{
+			Source: (&mock.Source{
+				Channels:   2,
+				SampleRate: 96000,
+			}).Source(),
+			Sink: (&mock.Sink{}).Sink().Accept(accepts),
+		},
+		pipe.SignalProperties{
+			Channels:   2,
+			SampleRate: 96000,
+		},
+	))
+}
+
+func TestConversion(t *testing.T) {
+	testConversion := func(source *mock.Source, accepts pipe.Accepts, expected []string, options ...pipe.LineOption) func(*testing.T) {
+		return func(t *testing.T) {
+			t.Helper()
+			sink := &mock.Sink{}
+			line, err := pipe.Routing{
+				Source: source.Source(),
+				Sink:   sink.Sink().Accept(accepts),
+			}.Line(bufferSize, options...)
+			assertNil(t, "error", err)
+
+			var names []string
+			for _, s := range line.Stages() {
+				names = append(names, s.Name)
+			}
+			assertEqual(t, "stages", names, expected)
+
+			err = pipe.NewRenderer(context.Background(), line).Render(1000)
+			assertEqual(t, "error", err, io.EOF)
+
+			props := line.Stages()[len(line.Stages())-1].Properties
+			expectedSamples := int(float64(source.Limit) * float64(props.SampleRate) / float64(source.SampleRate))
+			assertEqual(t, "channels", sink.Values.Channels(), props.Channels)
+			// last output sample might require input after the end of
+			// stream.
+			missing := expectedSamples - sink.Samples
+			assertEqual(t, "samples", missing == 0 || missing == 1, true)
+			assertEqual(t, "value", sink.Values.Sample(sink.Values.Len()/2), source.Value)
+		}
+	}
+	t.Run("upmix and upsample", testConversion(
+		&mock.Source{
+			Limit:      44100,
+			Channels:   1,
+			SampleRate: 44100,
+			Value:      0.5,
+		},
+		pipe.Accepts{
+			SampleRates: []signal.Frequency{48000},
+			Channels:    []int{2},
+		},
+		[]string{"source", "resampler 44100->48000 Hz", "channel mixer 1->2", "sink"},
+		pipe.Conversion(),
+	))
+	t.Run("downmix and downsample", testConversion(
+		&mock.Source{
+			Limit:      96000,
+			Channels:   2,
+			SampleRate: 96000,
+			Value:      0.5,
+		},
+		pipe.Accepts{
+			SampleRates: []signal.Frequency{48000},
+			Channels:    []int{1},
+		},
+		[]string{"source", "channel mixer 2->1", "resampler 96000->48000 Hz", "sink"},
+		pipe.Conversion(),
+	))
+	t.Run("custom matrix", testConversion(
+		&mock.Source{
+			Limit:      bufferSize,
+			Channels:   1,
+			SampleRate: 44100,
+			Value:      1,
+		},
+		pipe.Accepts{
+			Channels: []int{2},
+		},
+		[]string{"source", "channel mixer 1->2", "sink"},
+		pipe.Conversion(pipe.ChannelMatrix{{1}, {1}}),
+	))
+
+	testMatrix := func(matrix pipe.ChannelMatrix) func(*testing.T) {
+		return func(t *testing.T) {
+			t.Helper()
+			_, err := pipe.Routing{
+				Source: (&mock.Source{Channels: 2, SampleRate: 44100}).Source(),
+				Sink:   (&mock.Sink{}).Sink().Accept(pipe.Accepts{Channels: []int{2}}),
+			}.Line(bufferSize, pipe.Conversion(matrix))
+			assertEqual(t, "error", err != nil, true)
+		}
+	}
+	t.Run("ragged matrix", testMatrix(pipe.ChannelMatrix{{0.5, 0.5}, {1}}))
+	t.Run("empty matrix", testMatrix(pipe.ChannelMatrix{}))
+}
+
+func TestForwardProperties(t *testing.T) {
+	source := func(bufferSize int) (pipe.Source, pipe.SignalProperties, error) {
+		s, props, err := (&mock.Source{
+			Channels:   2,
+			SampleRate: 44100,
+		}).Source()(bufferSize)
+		props.Layout = pipe.StereoLayout
+		props.BitDepth = signal.BitDepth16
+		props.Length = 44100
+		props.Metadata = map[string]string{"title": "test"}
+		return s, props, err
+	}
+	override := func(bufferSize int, props pipe.SignalProperties) (pipe.Processor, pipe.SignalProperties, error) {
+		p, _, err := (&mock.Processor{}).Processor()(bufferSize, props)
+		return p, pipe.SignalProperties{
+			SampleRate: props.SampleRate,
+			Channels:   props.Channels,
+			Metadata:   map[string]string{"title": "override"},
+		}, err
+	}
+	line, err := pipe.Routing{
+		Source: source,
+		Processors: pipe.Processors(
+			pipe.ProcessorAllocatorFunc(override),
+			(&mock.Processor{}).Processor().Accept(pipe.Accepts{
+				SampleRates: []signal.Frequency{48000},
+				Channels:    []int{1},
+			}),
+		),
+		Sink: (&mock.Sink{}).Sink(),
+	}.Line(bufferSize, pipe.Conversion())
+	assertNil(t, "error", err)
+
+	stages := line.Stages()
+	assertEqual(t, "stages", len(stages), 6)
+	assertEqual(t, "override", stages[1].Properties, pipe.SignalProperties{
+		SampleRate: 44100,
+		Channels:   2,
+		Layout:     pipe.StereoLayout,
+		BitDepth:   signal.BitDepth16,
+		Length:     44100,
+		Metadata:   map[string]string{"title": "override"},
+	})
+	assertEqual(t, "converted", stages[5].Properties, pipe.SignalProperties{
+		SampleRate: 48000,
+		Channels:   1,
+		BitDepth:   signal.BitDepth16,
+		Length:     48000,
+		Metadata:   map[string]string{"title": "override"},
+	})
+	stages[3].Properties.Metadata["title"] = "changed"
+	assertEqual(t, "forwarded metadata", stages[2].Properties.Metadata["title"], "override")
+
+	_, err = pipe.Routing{
+		Source: func(bufferSize int) (pipe.Source, pipe.SignalProperties, error) {
+			s, props, err := source(bufferSize)
+			props.Layout = pipe.AmbisonicLayout(1)
+			return s, props, err
+		},
+		Sink: (&mock.Sink{}).Sink(),
+	}.Line(bufferSize)
+	assertEqual(t, "layout error", errors.Is(err, pipe.ErrInvalidProperties), true)
+}
+
+func TestChannelLayoutString(t *testing.T) {
+	for _, c := range []struct {
+		layout   pipe.ChannelLayout
+		expected string
+	}{
+		{pipe.StereoLayout, "stereo"},
+		{pipe.AmbisonicLayout(1), "ambisonic order 1"},
+		{pipe.ChannelLayout(7), "layout 7"},
+	} {
+		assertEqual(t, "layout", c.layout.String(), c.expected)
+	}
+}
+
+func TestLinesSampleRate(t *testing.T) {
+	route := func(sampleRate signal.Frequency) pipe.Routing {
+		return pipe.Routing{
+			Source: (&mock.Source{
+				Limit:      bufferSize,
+				Channels:   2,
+				SampleRate: sampleRate,
+			}).Source(),
+			Sink: (&mock.Sink{}).Sink(),
+		}
+	}
+	_, err := pipe.Lines(bufferSize, route(44100), route(48000))
+	assertEqual(t, "lines error", errors.Is(err, pipe.ErrInvalidProperties), true)
+
+	lines := make([]*pipe.Line, 2)
+	for i, sampleRate := range []signal.Frequency{44100, 48000} {
+		lines[i], err = route(sampleRate).Line(bufferSize)
+		assertNil(t, "error", err)
+	}
+	err = pipe.New(context.Background(), pipe.WithLines(lines...)).Wait()
+	assertEqual(t, "pipe error", errors.Is(err, pipe.ErrInvalidProperties), true)
+
+	p := pipe.New(context.Background(), pipe.WithLines(lines[0]))
+	assertNil(t, "error", p.Wait())
+	err = p.AddLine(lines[1]).Apply()
+	assertEqual(t, "add line error", errors.Is(err, pipe.ErrInvalidProperties), true)
+}
+
+func TestLatency(t *testing.T) {
+	withLatency := func(latency int) pipe.ProcessorAllocatorFunc {
+		return func(bufferSize int, props pipe.SignalProperties) (pipe.Processor, pipe.SignalProperties, error) {
+			p, props, err := (&mock.Processor{}).Processor()(bufferSize, props)
+			p.Latency = latency
+			return p, props, err
+		}
+	}
+	route := func(sink *mock.Sink, processors ...pipe.ProcessorAllocatorFunc) pipe.Routing {
+		return pipe.Routing{
+			Source: (&mock.Source{
+				Limit:      4 * bufferSize,
+				Channels:   2,
+				SampleRate: 44100,
+				Value:      1,
+			}).Source(),
+			Processors: processors,
+			Sink:       sink.Sink(),
+		}
+	}
+	sink1, sink2 := &mock.Sink{}, &mock.Sink{}
+	lines, err := pipe.Lines(bufferSize,
+		route(sink1, withLatency(64), withLatency(36)),
+		route(sink2, withLatency(20)),
+	)
+	assertNil(t, "error", err)
+	assertEqual(t, "latency 1", lines[0].Latency(), 100)
+	assertEqual(t, "latency 2", lines[1].Latency(), 20)
+
+	err = pipe.AlignLatency(lines...)
+	assertNil(t, "error", err)
+	assertEqual(t, "aligned latency 1", lines[0].Latency(), 100)
+	assertEqual(t, "aligned latency 2", lines[1].Latency(), 100)
+	stages := lines[1].Stages()
+	assertEqual(t, "delay stage", stages[len(stages)-2].Name, "delay 80 samples")
+
+	p := pipe.New(context.Background(), pipe.WithLines(lines...))
+	err = p.Wait()
+	assertNil(t, "error", err)
+	assertEqual(t, "line 1 first", sink1.Values.Sample(0), 1.0)
+	assertEqual(t, "line 2 delayed", sink2.Values.Sample(sink2.Values.BufferIndex(1, 79)), 0.0)
+	assertEqual(t, "line 2 first", sink2.Values.Sample(sink2.Values.BufferIndex(1, 80)), 1.0)
+	assertEqual(t, "samples", sink2.Samples, 4*bufferSize+80)
+
+	_, err = pipe.Routing{
+		Source: (&mock.Source{
+			Channels:   2,
+			SampleRate: 44100,
+		}).Source(),
+		Processors: pipe.Processors(withLatency(-1)),
+		Sink:       (&mock.Sink{}).Sink(),
+	}.Line(bufferSize)
+	assertEqual(t, "negative latency", err != nil, true)
+}
+
+func TestProcessorLength(t *testing.T) {
+	// decimator keeps every second sample.
+	decimator := func(bufferSize int) pipe.ProcessorAllocatorFunc {
+		return func(_ int, props pipe.SignalProperties) (pipe.Processor, pipe.SignalProperties, error) {
+			return pipe.Processor{
+				ProcessFunc: func(in, out signal.Floating) error {
+					for i := 0; i < out.Length(); i++ {
+						for c := 0; c < in.Channels(); c++ {
+							out.SetSample(out.BufferIndex(c, i), in.Sample(in.BufferIndex(c, 2*i)))
+						}
+					}
+					return nil
+				},
+				Length:     func(n int) int { return n / 2 },
+				BufferSize: bufferSize,
+			}, pipe.SignalProperties{
+				SampleRate: props.SampleRate / 2,
+				Channels:   props.Channels,
+			}, nil
+		}
+	}
+	source := &mock.Source{
+		Limit:      4 * bufferSize,
+		Channels:   2,
+		SampleRate: 44100,
+		Value:      0.5,
+	}
+	sink := &mock.Sink{}
+	line, err := pipe.Routing{
+		Source:     source.Source(),
+		Processors: pipe.Processors(decimator(bufferSize/2), (&mock.Processor{}).Processor()),
+		Sink:       sink.Sink(),
+	}.Line(bufferSize)
+	assertNil(t, "error", err)
+	stages := line.Stages()
+	assertEqual(t, "processor buffer size", stages[2].BufferSize, bufferSize/2)
+	assertEqual(t, "sink buffer size", stages[3].BufferSize, bufferSize/2)
+
+	err = pipe.New(context.Background(), pipe.WithLines(line)).Wait()
+	assertNil(t, "error", err)
+	assertEqual(t, "samples", sink.Samples, 2*bufferSize)
+	assertEqual(t, "value", sink.Values.Sample(sink.Values.Len()-1), 0.5)
+
+	_, err = pipe.Routing{
+		Source:     source.Source(),
+		Processors: pipe.Processors(decimator(0)),
+		Sink:       (&mock.Sink{}).Sink(),
+	}.Line(bufferSize)
+	assertEqual(t, "invalid buffer size", err != nil, true)
+}
+
+func TestReblock(t *testing.T) {
+	const limit = 10*bufferSize + 100
+	// reads are the lengths of source reads, full buffers are read if empty.
+	testReblock := func(lineSize, blockSize int, reads []int, run func(*pipe.Line, mutability.Mutation) error) func(*testing.T) {
+		return func(t *testing.T) {
+			t.Helper()
+			// ramp source, so the order of samples is checked.
+			var n, calls int
+			source := func(bufferSize int) (pipe.Source, pipe.SignalProperties, error) {
+				return pipe.Source{
+					SourceFunc: func(out signal.Floating) (int, error) {
+						if n == limit {
+							return 0, io.EOF
+						}
+						read := out.Length()
+						if len(reads) > 0 {
+							read = reads[calls%len(reads)]
+							calls++
+						}
+						if left := limit - n; left < read {
+							read = left
+						}
+						for i := 0; i < read; i++ {
+							n++
+							out.SetSample(out.BufferIndex(0, i), float64(n))
+							out.SetSample(out.BufferIndex(1, i), float64(n))
+						}
+						return read, nil
+					},
+				}, pipe.SignalProperties{
+					SampleRate: 44100,
+					Channels:   2,
+				}, nil
+			}
+			// checker counts blocks of unexpected size.
+			var partial int
+			checker := func(bufferSize int, props pipe.SignalProperties) (pipe.Processor, pipe.SignalProperties, error) {
+				assertEqual(t, "block size", bufferSize, blockSize)
+				return pipe.Processor{
+					ProcessFunc: func(in, out signal.Floating) error {
+						if in.Length() != blockSize {
+							partial++
+						}
+						signal.FloatingAsFloating(in, out)
+						return nil
+					},
+				}, props, nil
+			}
+			nested := &mock.Processor{
+				Mutator: mock.Mutator{
+					Mutability: mutability.Mutable(),
+				},
+			}
+			sink := &mock.Sink{}
+			line, err := pipe.Routing{
+				Source:     source,
+				Processors: pipe.Processors(pipe.Reblock(blockSize, checker, nested.Processor())),
+				Sink:       sink.Sink(),
+			}.Line(lineSize)
+			assertNil(t, "error", err)
+			latency := blockSize
+			assertEqual(t, "latency", line.Latency(), latency)
+
+			err = run(line, nested.MockMutation())
+			assertNil(t, "error", err)
+			assertEqual(t, "mutated", nested.Mutated, true)
+			assertEqual(t, "flushed", nested.Flushed, true)
+			assertEqual(t, "partial blocks", partial, 0)
+			assertEqual(t, "samples", sink.Samples, limit+latency)
+			for i := 0; i < latency; i++ {
+				assertEqual(t, "delayed", sink.Values.Sample(sink.Values.BufferIndex(1, i)), 0.0)
+			}
+			for i := 0; i < limit; i++ {
+				assertEqual(t, "value", sink.Values.Sample(sink.Values.BufferIndex(1, i+latency)), float64(i+1))
+			}
+		}
+	}
+	render := func(l *pipe.Line, m mutability.Mutation) error {
+		if err := pipe.NewRenderer(context.Background(), l, m).Render(1000); err != io.EOF {
+			return err
+		}
+		return nil
+	}
+	t.Run("split", testReblock(1024, 256, nil, render))
+	t.Run("accumulate", testReblock(256, 1024, nil, render))
+	t.Run("unaligned", testReblock(500, 128, nil, render))
+	t.Run("variable", testReblock(1024, 256, []int{1024, 100, 700, 1}, render))
+	t.Run("async", testReblock(500, 128, nil, func(l *pipe.Line, m mutability.Mutation) error {
+		return pipe.New(context.Background(), pipe.WithLines(l), pipe.WithMutations(m)).Wait()
+	}))
+
+	testError := func(processor pipe.ProcessorAllocatorFunc) func(*testing.T) {
+		return func(t *testing.T) {
+			t.Helper()
+			_, err := pipe.Routing{
+				Source: (&mock.Source{
+					Channels:   2,
+					SampleRate: 44100,
+				}).Source(),
+				Processors: pipe.Processors(processor),
+				Sink:       (&mock.Sink{}).Sink(),
+			}.Line(bufferSize)
+			assertEqual(t, "error", err != nil, true)
+		}
+	}
+	t.Run("block size", testError(pipe.Reblock(0)))
+	t.Run("nested error", testError(pipe.Reblock(256, (&mock.Processor{ErrorOnMake: errors.New("test")}).Processor())))
+	t.Run("nested tail", testError(pipe.Reblock(256, (&mock.Processor{Tail: -1}).Processor())))
+}
+
+func TestReblockLength(t *testing.T) {
+	testLength := func(lineSize, blockSize, limit, expected int) func(*testing.T) {
+		return func(t *testing.T) {
+			t.Helper()
+			sink := &mock.Sink{Discard: true}
+			line, err := pipe.Routing{
+				Source: (&mock.Source{
+					Limit:      limit,
+					Channels:   2,
+					SampleRate: 44100,
+				}).Source(),
+				Processors: pipe.Processors(pipe.Reblock(blockSize, (&mock.Processor{Tail: 10}).Processor())),
+				Sink:       sink.Sink(),
+			}.Line(lineSize)
+			assertNil(t, "error", err)
+			err = pipe.NewRenderer(context.Background(), line).Render(1000)
+			assertEqual(t, "error", err, io.EOF)
+			assertEqual(t, "samples", sink.Samples, expected)
+		}
+	}
+	// signal, delay of the block and nested tail.
+	t.Run("split", testLength(300, 100, 1001, 1001+100+10))
+	t.Run("unaligned", testLength(512, 100, 1001, 1001+100+10))
+	t.Run("short", testLength(256, 1024, 100, 100+1024+10))
+}
+
+func ExampleDOT() {
+	line, _ := pipe.Routing{
+		Source: (&mock.Source{
+			Channels:   1,
+			SampleRate: 44100,
+		}).Source(),
+		Processors: pipe.Processors(
+			(&mock.Processor{}).Processor().Accept(pipe.Accepts{
+				Channels: []int{2},
+			}),
+		),
+		Sink: (&mock.Sink{}).Sink(),
+	}.Line(bufferSize, pipe.Conversion())
+	_ = pipe.DOT(os.Stdout, line)
+	// Output:
+	// digraph pipe {
+	// 	rankdir=LR;
+	// 	subgraph cluster_0 {
+	// 		label="line 0";
+	// 		l0s0 [label="source"];
+	// 		l0s1 [label="channel mixer 1->2"];
+	// 		l0s2 [label="processor 0"];
+	// 		l0s3 [label="sink"];
+	// 		l0s0 -> l0s1 [label="44100 Hz, 1 ch"];
+	// 		l0s1 -> l0s2 [label="44100 Hz, 2 ch"];
+	// 		l0s2 -> l0s3 [label="44100 Hz, 2 ch"];
+	// 	}
+	// }
+}
+
 func assertNil(t *testing.T, name string, result interface{}) {
 	t.Helper()
 	assertEqual(t, name, result, nil)
@@ -0,0 +1,155 @@
+package processors
+
+import (
+	"fmt"
+
+	"pipelined.dev/signal"
+
+	"pipelined.dev/pipe"
+	"pipelined.dev/pipe/internal/allocation"
+	"pipelined.dev/pipe/mutability"
+)
+
+// Router routes input channels to the output. Each element of Channels
+// is the index of the input channel for the output channel with the
+// same index. Input channels can be reordered, dropped or duplicated.
+type Router struct {
+	Channels []int
+
+	last allocation.Last[routing]
+}
+
+// routing holds the channels of the allocated router.
+type routing struct {
+	channels []int
+	inputs   int
+}
+
+// Swap returns router that swaps two channels of stereo signal.
+func Swap() *Router {
+	return &Router{Channels: []int{1, 0}}
+}
+
+// Select returns router that keeps only provided channels.
+func Select(channels ...int) *Router {
+	return &Router{Channels: channels}
+}
+
+// Duplicate returns router that copies the channel into n output
+// channels.
+func Duplicate(channel, n int) *Router {
+	channels := make([]int, n)
+	for i := range channels {
+		channels[i] = channel
+	}
+	return &Router{Channels: channels}
+}
+
+// SetChannels returns mutation that changes the routing. The number of
+// output channels can't be changed.
+func (r *Router) SetChannels(channels ...int) mutability.Mutation {
+	channels = append([]int(nil), channels...)
+	return r.last.Mutate(func(rt *routing) error {
+		if len(channels) != len(rt.channels) {
+			return fmt.Errorf("routing must have %d channels, got %d", len(rt.channels), len(channels))
+		}
+		if err := validChannels(channels, rt.inputs); err != nil {
+			return err
+		}
+		rt.channels = channels
+		return nil
+	})
+}
+
+// Processor returns allocator of the router processor.
+func (r *Router) Processor() pipe.ProcessorAllocatorFunc {
+	return func(bufferSize int, props pipe.SignalProperties) (pipe.Processor, pipe.SignalProperties, error) {
+		if len(r.Channels) == 0 {
+			return pipe.Processor{}, pipe.SignalProperties{}, fmt.Errorf("routing has no channels")
+		}
+		if err := validChannels(r.Channels, props.Channels); err != nil {
+			return pipe.Processor{}, pipe.SignalProperties{}, err
+		}
+		rt := routing{
+			channels: append([]int(nil), r.Channels...),
+			inputs:   props.Channels,
+		}
+		return pipe.Processor{
+			Mutability: r.last.Set(&rt),
+			ProcessFunc: func(in, out signal.Floating) error {
+				for i := 0; i < in.Length(); i++ {
+					for o, c := range rt.channels {
+						out.SetSample(out.BufferIndex(o, i), in.Sample(in.BufferIndex(c, i)))
+					}
+				}
+				return nil
+			},
+		}, pipe.SignalProperties{
+			SampleRate: props.SampleRate,
+			Channels:   len(r.Channels),
+		}, nil
+	}
+}
+
+func validChannels(channels []int, inputs int) error {
+	for _, c := range channels {
+		if c < 0 || c >= inputs {
+			return fmt.Errorf("invalid channel %d for %d input channels", c, inputs)
+		}
+	}
+	return nil
+}
+
+// MidSide converts stereo signal into mid/side and back. Encoder
+// outputs mid in the first channel and side in the second one, decoder
+// expects the same order. Side is scaled by SideDecibels, which allows
+// to change the stereo width.
+type MidSide struct {
+	Decode       bool
+	SideDecibels float64
+
+	last allocation.Last[float64]
+}
+
+// SetSideDecibels returns mutation that changes the side gain.
+func (m *MidSide) SetSideDecibels(db float64) mutability.Mutation {
+	return m.last.Mutate(func(side *float64) error {
+		if err := validGain(db); err != nil {
+			return err
+		}
+		*side = db
+		return nil
+	})
+}
+
+// Processor returns allocator of the mid/side processor.
+func (m *MidSide) Processor() pipe.ProcessorAllocatorFunc {
+	var allocator pipe.ProcessorAllocatorFunc = func(bufferSize int, props pipe.SignalProperties) (pipe.Processor, pipe.SignalProperties, error) {
+		if err := validGain(m.SideDecibels); err != nil {
+			return pipe.Processor{}, pipe.SignalProperties{}, err
+		}
+		db, decode := m.SideDecibels, m.Decode
+		side := newRamp(props.SampleRate, decibels(db))
+		return pipe.Processor{
+			Mutability: m.last.Set(&db),
+			ProcessFunc: func(in, out signal.Floating) error {
+				side.set(decibels(db))
+				for i := 0; i < in.Length(); i++ {
+					a, b := in.Sample(in.BufferIndex(0, i)), in.Sample(in.BufferIndex(1, i))
+					if decode {
+						// a is mid and b is side.
+						b *= side.next()
+						a, b = a+b, a-b
+					} else {
+						// a is left and b is right.
+						a, b = (a+b)/2, (a-b)/2*side.next()
+					}
+					out.SetSample(out.BufferIndex(0, i), a)
+					out.SetSample(out.BufferIndex(1, i), b)
+				}
+				return nil
+			},
+		}, props, nil
+	}
+	return allocator.Accept(pipe.Accepts{Channels: []int{2}})
+}
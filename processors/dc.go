@@ -0,0 +1,82 @@
+package processors
+
+import (
+	"fmt"
+	"math"
+
+	"pipelined.dev/signal"
+
+	"pipelined.dev/pipe"
+	"pipelined.dev/pipe/internal/allocation"
+	"pipelined.dev/pipe/mutability"
+)
+
+// defaultCutoff is the cutoff frequency of DCBlock if it's not set.
+const defaultCutoff = 10
+
+// DCBlock removes DC offset with one-pole high-pass filter. Cutoff is
+// the cutoff frequency in Hz, 10 Hz is used if it's zero.
+type DCBlock struct {
+	Cutoff float64
+
+	last allocation.Last[dcBlock]
+}
+
+// dcBlock holds the parameters of the allocated processor.
+type dcBlock struct {
+	cutoff     float64
+	sampleRate signal.Frequency
+}
+
+// SetCutoff returns mutation that changes the cutoff frequency.
+func (d *DCBlock) SetCutoff(cutoff float64) mutability.Mutation {
+	return d.last.Mutate(func(dc *dcBlock) error {
+		if err := validCutoff(cutoff, dc.sampleRate); err != nil {
+			return err
+		}
+		dc.cutoff = cutoff
+		return nil
+	})
+}
+
+// Processor returns allocator of the DC offset removal processor.
+func (d *DCBlock) Processor() pipe.ProcessorAllocatorFunc {
+	return func(bufferSize int, props pipe.SignalProperties) (pipe.Processor, pipe.SignalProperties, error) {
+		dc := dcBlock{
+			cutoff:     d.Cutoff,
+			sampleRate: props.SampleRate,
+		}
+		if dc.cutoff == 0 {
+			dc.cutoff = defaultCutoff
+		}
+		if err := validCutoff(dc.cutoff, dc.sampleRate); err != nil {
+			return pipe.Processor{}, pipe.SignalProperties{}, err
+		}
+		// previous input and output of each channel.
+		x := make([]float64, props.Channels)
+		y := make([]float64, props.Channels)
+		return pipe.Processor{
+			Mutability: d.last.Set(&dc),
+			ProcessFunc: func(in, out signal.Floating) error {
+				r := math.Exp(-2 * math.Pi * dc.cutoff / float64(dc.sampleRate))
+				for i := 0; i < in.Length(); i++ {
+					for c := range x {
+						idx := in.BufferIndex(c, i)
+						v := in.Sample(idx)
+						y[c] = v - x[c] + r*y[c]
+						x[c] = v
+						out.SetSample(idx, y[c])
+					}
+				}
+				return nil
+			},
+		}, props, nil
+	}
+}
+
+func validCutoff(cutoff float64, sampleRate signal.Frequency) error {
+	if !(cutoff > 0 && cutoff < float64(sampleRate)/2) {
+		return fmt.Errorf("invalid cutoff %v Hz for %v Hz sample rate", cutoff, sampleRate)
+	}
+	return nil
+}
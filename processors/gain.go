@@ -0,0 +1,108 @@
+package processors
+
+import (
+	"fmt"
+	"math"
+
+	"pipelined.dev/signal"
+
+	"pipelined.dev/pipe"
+	"pipelined.dev/pipe/internal/allocation"
+	"pipelined.dev/pipe/mutability"
+)
+
+// Gain changes the level of the signal by Decibels. Negative infinity
+// mutes the signal.
+type Gain struct {
+	Decibels float64
+
+	last allocation.Last[float64]
+}
+
+// SetDecibels returns mutation that changes the gain.
+func (g *Gain) SetDecibels(db float64) mutability.Mutation {
+	return g.last.Mutate(func(decibels *float64) error {
+		if err := validGain(db); err != nil {
+			return err
+		}
+		*decibels = db
+		return nil
+	})
+}
+
+// Processor returns allocator of the gain processor.
+func (g *Gain) Processor() pipe.ProcessorAllocatorFunc {
+	return func(bufferSize int, props pipe.SignalProperties) (pipe.Processor, pipe.SignalProperties, error) {
+		if err := validGain(g.Decibels); err != nil {
+			return pipe.Processor{}, pipe.SignalProperties{}, err
+		}
+		db := g.Decibels
+		gain := newRamp(props.SampleRate, decibels(db))
+		return pipe.Processor{
+			Mutability: g.last.Set(&db),
+			ProcessFunc: func(in, out signal.Floating) error {
+				gain.set(decibels(db))
+				apply(in, out, gain.next)
+				return nil
+			},
+		}, props, nil
+	}
+}
+
+// Polarity inverts the polarity of the signal if Invert is true.
+type Polarity struct {
+	Invert bool
+
+	last allocation.Last[bool]
+}
+
+// SetInvert returns mutation that changes the polarity.
+func (p *Polarity) SetInvert(invert bool) mutability.Mutation {
+	return p.last.Mutate(func(inverted *bool) error {
+		*inverted = invert
+		return nil
+	})
+}
+
+// Processor returns allocator of the polarity processor.
+func (p *Polarity) Processor() pipe.ProcessorAllocatorFunc {
+	return func(bufferSize int, props pipe.SignalProperties) (pipe.Processor, pipe.SignalProperties, error) {
+		invert := p.Invert
+		gain := newRamp(props.SampleRate, polarity(invert))
+		return pipe.Processor{
+			Mutability: p.last.Set(&invert),
+			ProcessFunc: func(in, out signal.Floating) error {
+				gain.set(polarity(invert))
+				apply(in, out, gain.next)
+				return nil
+			},
+		}, props, nil
+	}
+}
+
+// polarity returns the gain of the polarity.
+func polarity(invert bool) float64 {
+	if invert {
+		return -1
+	}
+	return 1
+}
+
+func validGain(db float64) error {
+	if math.IsNaN(db) || math.IsInf(db, 1) {
+		return fmt.Errorf("invalid gain %v dB", db)
+	}
+	return nil
+}
+
+// apply multiplies all channels of each sample by the gain.
+func apply(in, out signal.Floating, gain func() float64) {
+	channels := in.Channels()
+	for i := 0; i < in.Length(); i++ {
+		g := gain()
+		for c := 0; c < channels; c++ {
+			idx := in.BufferIndex(c, i)
+			out.SetSample(idx, in.Sample(idx)*g)
+		}
+	}
+}
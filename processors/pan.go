@@ -0,0 +1,66 @@
+package processors
+
+import (
+	"fmt"
+	"math"
+
+	"pipelined.dev/signal"
+
+	"pipelined.dev/pipe"
+	"pipelined.dev/pipe/internal/allocation"
+	"pipelined.dev/pipe/mutability"
+)
+
+// Pan places mono signal into stereo field with equal-power law.
+// Position is in range [-1, 1], where -1 is left, 0 is center and 1 is
+// right. Processor accepts mono input, so line conversion can downmix
+// the signal before it.
+type Pan struct {
+	Position float64
+
+	last allocation.Last[float64]
+}
+
+// SetPosition returns mutation that changes the position.
+func (p *Pan) SetPosition(position float64) mutability.Mutation {
+	return p.last.Mutate(func(current *float64) error {
+		if !validPosition(position) {
+			return fmt.Errorf("invalid pan position %v", position)
+		}
+		*current = position
+		return nil
+	})
+}
+
+// Processor returns allocator of the pan processor.
+func (p *Pan) Processor() pipe.ProcessorAllocatorFunc {
+	var allocator pipe.ProcessorAllocatorFunc = func(bufferSize int, props pipe.SignalProperties) (pipe.Processor, pipe.SignalProperties, error) {
+		if !validPosition(p.Position) {
+			return pipe.Processor{}, pipe.SignalProperties{}, fmt.Errorf("invalid pan position %v", p.Position)
+		}
+		target := p.Position
+		position := newRamp(props.SampleRate, target)
+		return pipe.Processor{
+			Mutability: p.last.Set(&target),
+			ProcessFunc: func(in, out signal.Floating) error {
+				position.set(target)
+				for i := 0; i < in.Length(); i++ {
+					angle := (position.next() + 1) * math.Pi / 4
+					v := in.Sample(i)
+					out.SetSample(out.BufferIndex(0, i), v*math.Cos(angle))
+					out.SetSample(out.BufferIndex(1, i), v*math.Sin(angle))
+				}
+				return nil
+			},
+		}, pipe.SignalProperties{
+			SampleRate: props.SampleRate,
+			Channels:   2,
+			Layout:     pipe.StereoLayout,
+		}, nil
+	}
+	return allocator.Accept(pipe.Accepts{Channels: []int{1}})
+}
+
+func validPosition(position float64) bool {
+	return position >= -1 && position <= 1
+}
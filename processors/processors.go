@@ -0,0 +1,70 @@
+// Package processors provides basic utility processors: gain, pan,
+// polarity, channel routing, mid/side, DC offset removal and sample rate
+// conversion.
+//
+// Processors are configured with exported fields and their parameters
+// can be changed with mutations while the pipe runs. Each allocated
+// processor copies the fields and has its own mutability, mutations are
+// applied to the last allocated processor and are no-op if it isn't
+// allocated yet. Changes of gain and position are smoothed, so they
+// don't produce clicks.
+package processors
+
+import (
+	"math"
+	"time"
+
+	"pipelined.dev/signal"
+)
+
+// smoothing is the duration of parameter transitions.
+const smoothing = 10 * time.Millisecond
+
+// ramp moves the value linearly to the target over the smoothing time.
+type ramp struct {
+	value  float64
+	target float64
+	step   float64
+	left   int
+	length int
+}
+
+// newRamp returns ramp that starts at provided value.
+func newRamp(sampleRate signal.Frequency, value float64) ramp {
+	length := sampleRate.Events(smoothing)
+	if length < 1 {
+		length = 1
+	}
+	return ramp{
+		value:  value,
+		target: value,
+		length: length,
+	}
+}
+
+// set starts the transition if the target has changed.
+func (r *ramp) set(target float64) {
+	if target == r.target {
+		return
+	}
+	r.target = target
+	r.left = r.length
+	r.step = (target - r.value) / float64(r.length)
+}
+
+// next returns the next value of the transition.
+func (r *ramp) next() float64 {
+	if r.left > 0 {
+		r.left--
+		r.value += r.step
+		if r.left == 0 {
+			r.value = r.target
+		}
+	}
+	return r.value
+}
+
+// decibels converts gain in decibels into linear one.
+func decibels(db float64) float64 {
+	return math.Pow(10, db/20)
+}
@@ -0,0 +1,303 @@
+package processors_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"reflect"
+	"testing"
+
+	"pipelined.dev/signal"
+
+	"pipelined.dev/pipe"
+	"pipelined.dev/pipe/generator"
+	"pipelined.dev/pipe/mock"
+	"pipelined.dev/pipe/mutability"
+	"pipelined.dev/pipe/processors"
+)
+
+const (
+	bufferSize = 512
+	sampleRate = 44100
+)
+
+// renderer returns renderer of the line with mock source and sink.
+func renderer(t *testing.T, channels int, value float64, processors ...pipe.ProcessorAllocatorFunc) (*pipe.Renderer, *mock.Sink) {
+	t.Helper()
+	source := &mock.Source{
+		Limit:      10 * bufferSize,
+		Channels:   channels,
+		SampleRate: sampleRate,
+		Value:      value,
+	}
+	sink := &mock.Sink{}
+	line, err := pipe.Routing{
+		Source:     source.Source(),
+		Processors: processors,
+		Sink:       sink.Sink(),
+	}.Line(bufferSize)
+	assertEqual(t, "line error", err, nil)
+	return pipe.NewRenderer(context.Background(), line), sink
+}
+
+// sample returns the value of the channel at position of the sink.
+func sample(sink *mock.Sink, channel, pos int) float64 {
+	return sink.Values.Sample(sink.Values.BufferIndex(channel, pos))
+}
+
+func TestGain(t *testing.T) {
+	gain := &processors.Gain{Decibels: -20}
+	r, sink := renderer(t, 2, 0.5, gain.Processor())
+	assertEqual(t, "render error", r.Next(), nil)
+	assertNear(t, "gain", sample(sink, 1, 0), 0.05)
+
+	// transition takes 441 samples.
+	r.Push(gain.SetDecibels(0))
+	assertEqual(t, "render error", r.Next(), nil)
+	assertEqual(t, "smoothed", sample(sink, 0, bufferSize) < 0.06, true)
+	assertNear(t, "transition", sample(sink, 0, bufferSize+220), 0.275)
+	assertNear(t, "done", sample(sink, 0, 2*bufferSize-1), 0.5)
+
+	r.Push(gain.SetDecibels(math.Inf(-1)))
+	assertEqual(t, "render error", r.Render(2), nil)
+	assertNear(t, "muted", sample(sink, 0, 4*bufferSize-1), 0)
+
+	r.Push(gain.SetDecibels(math.NaN()))
+	assertEqual(t, "mutation error", r.Next() != nil, true)
+}
+
+func TestPolarity(t *testing.T) {
+	polarity := &processors.Polarity{Invert: true}
+	r, sink := renderer(t, 1, 0.5, polarity.Processor())
+	assertEqual(t, "render error", r.Next(), nil)
+	assertNear(t, "inverted", sample(sink, 0, 0), -0.5)
+
+	r.Push(polarity.SetInvert(false))
+	assertEqual(t, "render error", r.Next(), nil)
+	assertNear(t, "restored", sample(sink, 0, 2*bufferSize-1), 0.5)
+}
+
+func TestPan(t *testing.T) {
+	pan := &processors.Pan{Position: -1}
+	r, sink := renderer(t, 1, 0.5, pan.Processor())
+	assertEqual(t, "render error", r.Next(), nil)
+	assertEqual(t, "channels", sink.Values.Channels(), 2)
+	assertNear(t, "left", sample(sink, 0, 0), 0.5)
+	assertNear(t, "right", sample(sink, 1, 0), 0)
+
+	r.Push(pan.SetPosition(0))
+	assertEqual(t, "render error", r.Next(), nil)
+	assertNear(t, "center left", sample(sink, 0, 2*bufferSize-1), 0.5*math.Sqrt2/2)
+	assertNear(t, "center right", sample(sink, 1, 2*bufferSize-1), 0.5*math.Sqrt2/2)
+
+	r.Push(pan.SetPosition(2))
+	assertEqual(t, "mutation error", r.Next() != nil, true)
+
+	_, err := pipe.Routing{
+		Source:     (&mock.Source{Channels: 2, SampleRate: sampleRate}).Source(),
+		Processors: pipe.Processors(pan.Processor()),
+		Sink:       (&mock.Sink{}).Sink(),
+	}.Line(bufferSize)
+	var unsupported *pipe.UnsupportedError
+	assertEqual(t, "unsupported", errors.As(err, &unsupported), true)
+}
+
+func TestRouter(t *testing.T) {
+	testRouter := func(router *processors.Router, expected [][]float64) func(*testing.T) {
+		return func(t *testing.T) {
+			t.Helper()
+			// pan to the left, so channels have different values.
+			r, sink := renderer(t, 1, 0.5, (&processors.Pan{Position: -1}).Processor(), router.Processor())
+			assertEqual(t, "render error", r.Next(), nil)
+			assertEqual(t, "channels", sink.Values.Channels(), len(expected))
+			for c := range expected {
+				assertNear(t, "value", sample(sink, c, 0), expected[c][0])
+			}
+		}
+	}
+	t.Run("swap", testRouter(processors.Swap(), [][]float64{{0}, {0.5}}))
+	t.Run("select", testRouter(processors.Select(0), [][]float64{{0.5}}))
+	t.Run("duplicate", testRouter(processors.Duplicate(0, 3), [][]float64{{0.5}, {0.5}, {0.5}}))
+
+	t.Run("mutation", func(t *testing.T) {
+		router := processors.Swap()
+		r, sink := renderer(t, 1, 0.5, (&processors.Pan{Position: -1}).Processor(), router.Processor())
+		r.Push(router.SetChannels(0, 0))
+		assertEqual(t, "render error", r.Next(), nil)
+		assertNear(t, "right", sample(sink, 1, 0), 0.5)
+
+		r.Push(router.SetChannels(0))
+		assertEqual(t, "mutation error", r.Next() != nil, true)
+	})
+
+	_, err := pipe.Routing{
+		Source:     (&mock.Source{Channels: 2, SampleRate: sampleRate}).Source(),
+		Processors: pipe.Processors(processors.Select(2).Processor()),
+		Sink:       (&mock.Sink{}).Sink(),
+	}.Line(bufferSize)
+	assertEqual(t, "invalid channel", err != nil, true)
+}
+
+func TestMidSide(t *testing.T) {
+	encoder := &processors.MidSide{}
+	r, sink := renderer(t, 1, 0.5, (&processors.Pan{Position: -1}).Processor(), encoder.Processor())
+	assertEqual(t, "render error", r.Next(), nil)
+	assertNear(t, "mid", sample(sink, 0, 0), 0.25)
+	assertNear(t, "side", sample(sink, 1, 0), 0.25)
+
+	r, sink = renderer(t, 1, 0.5,
+		(&processors.Pan{Position: -1}).Processor(),
+		(&processors.MidSide{}).Processor(),
+		(&processors.MidSide{Decode: true}).Processor(),
+	)
+	assertEqual(t, "render error", r.Next(), nil)
+	assertNear(t, "left", sample(sink, 0, 0), 0.5)
+	assertNear(t, "right", sample(sink, 1, 0), 0)
+
+	// no side results in mono.
+	r, sink = renderer(t, 1, 0.5, (&processors.Pan{Position: -1}).Processor(), encoder.Processor())
+	r.Push(encoder.SetSideDecibels(math.Inf(-1)))
+	assertEqual(t, "render error", r.Next(), nil)
+	assertNear(t, "no side", sample(sink, 1, bufferSize-1), 0)
+
+	r.Push(encoder.SetSideDecibels(math.NaN()))
+	assertEqual(t, "mutation error", r.Next() != nil, true)
+
+	_, err := pipe.Routing{
+		Source:     (&mock.Source{Channels: 2, SampleRate: sampleRate}).Source(),
+		Processors: pipe.Processors((&processors.MidSide{SideDecibels: math.Inf(1)}).Processor()),
+		Sink:       (&mock.Sink{}).Sink(),
+	}.Line(bufferSize)
+	assertEqual(t, "allocation error", err != nil, true)
+}
+
+func TestDCBlock(t *testing.T) {
+	dc := &processors.DCBlock{}
+	r, sink := renderer(t, 2, 0.5, dc.Processor())
+	assertEqual(t, "render error", r.Render(20), io.EOF)
+	assertNear(t, "first", sample(sink, 0, 0), 0.5)
+	assertEqual(t, "decayed", math.Abs(sample(sink, 1, 10*bufferSize-1)) < 0.1, true)
+	assertEqual(t, "cutoff unchanged", dc.Cutoff, 0.0)
+
+	r, _ = renderer(t, 2, 0.5, dc.Processor())
+	r.Push(dc.SetCutoff(sampleRate))
+	assertEqual(t, "mutation error", r.Next() != nil, true)
+}
+
+func TestResampler(t *testing.T) {
+	testResampler := func(from, to signal.Frequency, quality processors.Quality, frequency, expected, delta float64) func(*testing.T) {
+		return func(t *testing.T) {
+			t.Helper()
+			source := &generator.Generator{
+				Waveform:   generator.Sine,
+				Frequency:  frequency,
+				Amplitude:  0.5,
+				Channels:   2,
+				SampleRate: from,
+				Length:     int(from) / 10,
+			}
+			resampler := &processors.Resampler{SampleRate: to, Quality: quality}
+			sink := &mock.Sink{}
+			line, err := pipe.Routing{
+				Source:     source.Source(),
+				Processors: pipe.Processors(resampler.Processor()),
+				Sink:       sink.Sink(),
+			}.Line(bufferSize)
+			assertEqual(t, "line error", err, nil)
+			assertEqual(t, "sample rate", line.Stages()[1].Properties.SampleRate, to)
+			assertEqual(t, "length", line.Stages()[1].Properties.Length, int(to)/10)
+			assertEqual(t, "render error", pipe.NewRenderer(context.Background(), line).Render(1000), io.EOF)
+
+			latency := line.Latency()
+			// tail flushes the delayed signal.
+			assertEqual(t, "samples", sink.Counter.Samples >= int(to)/10+latency, true)
+			// edges are skipped, where the signal starts and ends abruptly.
+			for i := 200; i < int(to)/10-200; i++ {
+				v := expected * math.Sin(2*math.Pi*frequency*float64(i)/float64(to))
+				if math.Abs(sample(sink, 1, i+latency)-v) > delta {
+					t.Fatalf("value %v at %d, expected %v", sample(sink, 1, i+latency), i, v)
+				}
+			}
+		}
+	}
+	t.Run("linear upsample", testResampler(44100, 48000, processors.Linear, 1000, 0.5, 1e-2))
+	t.Run("sinc upsample", testResampler(44100, 48000, processors.Sinc, 1000, 0.5, 1e-3))
+	t.Run("sinc downsample", testResampler(96000, 44100, processors.Sinc, 1000, 0.5, 1e-3))
+	// frequency above the output Nyquist frequency is removed.
+	t.Run("sinc anti-aliasing", testResampler(96000, 48000, processors.Sinc, 30000, 0, 1e-2))
+
+	t.Run("bypass", func(t *testing.T) {
+		line, err := pipe.Routing{
+			Source:     (&mock.Source{Channels: 2, SampleRate: sampleRate}).Source(),
+			Processors: pipe.Processors((&processors.Resampler{SampleRate: 48000}).Processor()),
+			Sink:       (&mock.Sink{}).Sink(),
+		}.Line(bufferSize)
+		assertEqual(t, "line error", err, nil)
+		_, err = line.Bypass(0, true, false)
+		assertEqual(t, "bypass error", err != nil, true)
+	})
+
+	testError := func(resampler *processors.Resampler) func(*testing.T) {
+		return func(t *testing.T) {
+			t.Helper()
+			_, err := pipe.Routing{
+				Source:     (&mock.Source{Channels: 2, SampleRate: sampleRate}).Source(),
+				Processors: pipe.Processors(resampler.Processor()),
+				Sink:       (&mock.Sink{}).Sink(),
+			}.Line(bufferSize)
+			assertEqual(t, "error", err != nil, true)
+		}
+	}
+	t.Run("sample rate", testError(&processors.Resampler{}))
+	t.Run("quality", testError(&processors.Resampler{SampleRate: 48000, Quality: 10}))
+
+	q, err := processors.ParseQuality("sinc")
+	assertEqual(t, "parse error", err, nil)
+	assertEqual(t, "quality", q.String(), "sinc")
+}
+
+func TestMutability(t *testing.T) {
+	gain, polarity, pan := &processors.Gain{}, &processors.Polarity{}, &processors.Pan{}
+	swap, midSide, dc := processors.Swap(), &processors.MidSide{}, &processors.DCBlock{}
+	tests := []struct {
+		name     string
+		channels int
+		alloc    pipe.ProcessorAllocatorFunc
+		mutation func() mutability.Mutation
+	}{
+		{"gain", 2, gain.Processor(), func() mutability.Mutation { return gain.SetDecibels(0) }},
+		{"polarity", 2, polarity.Processor(), func() mutability.Mutation { return polarity.SetInvert(true) }},
+		{"pan", 1, pan.Processor(), func() mutability.Mutation { return pan.SetPosition(0) }},
+		{"router", 2, swap.Processor(), func() mutability.Mutation { return swap.SetChannels(0, 1) }},
+		{"mid/side", 2, midSide.Processor(), func() mutability.Mutation { return midSide.SetSideDecibels(0) }},
+		{"dc block", 2, dc.Processor(), func() mutability.Mutation { return dc.SetCutoff(10) }},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assertEqual(t, "not allocated", test.mutation(), mutability.Mutation{})
+
+			props := pipe.SignalProperties{Channels: test.channels, SampleRate: sampleRate}
+			first, _, err := test.alloc(bufferSize, props)
+			assertEqual(t, "first error", err, nil)
+			second, _, err := test.alloc(bufferSize, props)
+			assertEqual(t, "second error", err, nil)
+			assertEqual(t, "unique", first.Mutability != second.Mutability, true)
+			assertEqual(t, "last allocated", test.mutation().Mutability, second.Mutability)
+		})
+	}
+}
+
+func assertNear(t *testing.T, name string, result, expected float64) {
+	t.Helper()
+	if math.Abs(result-expected) > 1e-3 {
+		t.Fatalf("%v\nresult: \t%v\nexpected: \t%v", name, result, expected)
+	}
+}
+
+func assertEqual(t *testing.T, name string, result, expected interface{}) {
+	t.Helper()
+	if !reflect.DeepEqual(expected, result) {
+		t.Fatalf("%v\nresult: \t%T\t%+v \nexpected: \t%T\t%+v", name, result, result, expected, expected)
+	}
+}
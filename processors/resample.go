@@ -0,0 +1,85 @@
+package processors
+
+import (
+	"fmt"
+
+	"pipelined.dev/signal"
+
+	"pipelined.dev/pipe"
+	"pipelined.dev/pipe/internal/resample"
+)
+
+// Quality is the interpolation method of the sample rate converter.
+type Quality uint8
+
+// Supported quality levels. Linear is cheap, but passes aliases of high
+// frequencies. Sinc uses windowed-sinc low-pass filter, which removes
+// them, but delays the signal.
+const (
+	Linear Quality = iota
+	Sinc
+)
+
+var qualities = [...]string{
+	Linear: "linear",
+	Sinc:   "sinc",
+}
+
+// String returns the name of the quality.
+func (q Quality) String() string {
+	if int(q) >= len(qualities) {
+		return fmt.Sprintf("quality %d", int(q))
+	}
+	return qualities[q]
+}
+
+// ParseQuality returns the quality with provided name.
+func ParseQuality(name string) (Quality, error) {
+	for q, n := range qualities {
+		if n == name {
+			return Quality(q), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown quality %q", name)
+}
+
+// Resampler converts the signal to another sample rate. The length of
+// output buffers varies, so processors and sink after the resampler
+// receive buffers of increased size. Input is buffered internally, the
+// latency of Sinc quality is reported by the processor and the end of
+// the signal is flushed with the processor tail.
+type Resampler struct {
+	SampleRate signal.Frequency
+	Quality    Quality
+}
+
+// Processor returns allocator of the resampler processor.
+func (r *Resampler) Processor() pipe.ProcessorAllocatorFunc {
+	return func(bufferSize int, props pipe.SignalProperties) (pipe.Processor, pipe.SignalProperties, error) {
+		if r.SampleRate <= 0 {
+			return pipe.Processor{}, pipe.SignalProperties{}, fmt.Errorf("invalid sample rate %v", r.SampleRate)
+		}
+		var resampler *resample.Resampler
+		switch r.Quality {
+		case Linear:
+			resampler = resample.Linear(props.Channels, props.SampleRate, r.SampleRate)
+		case Sinc:
+			resampler = resample.Sinc(props.Channels, props.SampleRate, r.SampleRate)
+		default:
+			return pipe.Processor{}, pipe.SignalProperties{}, fmt.Errorf("unsupported quality %v", r.Quality)
+		}
+		return pipe.Processor{
+			ProcessFunc: func(in, out signal.Floating) error {
+				resampler.Process(in, out)
+				return nil
+			},
+			Latency:    resampler.Latency(),
+			Tail:       resampler.Tail(),
+			Length:     resampler.Length,
+			BufferSize: resampler.BufferSize(bufferSize),
+		}, pipe.SignalProperties{
+			SampleRate: r.SampleRate,
+			Channels:   props.Channels,
+		}, nil
+	}
+}
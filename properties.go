@@ -0,0 +1,221 @@
+package pipe
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"pipelined.dev/signal"
+)
+
+// ErrInvalidProperties is returned when component provides signal
+// properties that can't be processed.
+var ErrInvalidProperties = errors.New("invalid signal properties")
+
+type (
+	// Accepts defines signal properties accepted by the component. Each
+	// field is a set of accepted values. Empty set means that any value
+	// is accepted, single value means that value is fixed.
+	Accepts struct {
+		SampleRates []signal.Frequency
+		Channels    []int
+	}
+
+	// UnsupportedError is returned when component doesn't accept input
+	// signal properties.
+	UnsupportedError struct {
+		Properties SignalProperties
+		Accepts    Accepts
+	}
+)
+
+// ChannelLayout describes the position of channels. Zero value means
+// that layout is unknown.
+type ChannelLayout uint16
+
+// Channel layouts.
+const (
+	UnknownLayout ChannelLayout = iota
+	MonoLayout
+	StereoLayout
+	QuadLayout
+	Surround51Layout
+	Surround71Layout
+)
+
+// ambisonicLayout is a base value of ambisonic layouts, the order is
+// added to it.
+const ambisonicLayout ChannelLayout = 1 << 8
+
+// AmbisonicLayout returns layout of ambisonic signal with provided
+// order.
+func AmbisonicLayout(order int) ChannelLayout {
+	return ambisonicLayout + ChannelLayout(order)
+}
+
+// Channels returns the number of channels in the layout. Zero is
+// returned if layout is unknown.
+func (l ChannelLayout) Channels() int {
+	switch l {
+	case UnknownLayout:
+		return 0
+	case MonoLayout:
+		return 1
+	case StereoLayout:
+		return 2
+	case QuadLayout:
+		return 4
+	case Surround51Layout:
+		return 6
+	case Surround71Layout:
+		return 8
+	}
+	if l < ambisonicLayout {
+		return 0
+	}
+	order := int(l - ambisonicLayout)
+	return (order + 1) * (order + 1)
+}
+
+func (l ChannelLayout) String() string {
+	switch l {
+	case UnknownLayout:
+		return "unknown"
+	case MonoLayout:
+		return "mono"
+	case StereoLayout:
+		return "stereo"
+	case QuadLayout:
+		return "quad"
+	case Surround51Layout:
+		return "5.1"
+	case Surround71Layout:
+		return "7.1"
+	}
+	if l < ambisonicLayout {
+		return fmt.Sprintf("layout %d", l)
+	}
+	return fmt.Sprintf("ambisonic order %d", l-ambisonicLayout)
+}
+
+// validate checks that signal properties are defined.
+func (p SignalProperties) validate() error {
+	if p.Channels <= 0 {
+		return fmt.Errorf("%w: %d channels", ErrInvalidProperties, p.Channels)
+	}
+	if p.SampleRate <= 0 {
+		return fmt.Errorf("%w: unknown sample rate %v", ErrInvalidProperties, p.SampleRate)
+	}
+	if p.Layout != UnknownLayout && p.Layout.Channels() != p.Channels {
+		return fmt.Errorf("%w: %v layout with %d channels", ErrInvalidProperties, p.Layout, p.Channels)
+	}
+	return nil
+}
+
+// checkSampleRates returns error if lines have different sample rates.
+// Lines of a pipe are chained through components, like mixer, so the
+// signal consumed by their sinks must have the same sample rate.
+func checkSampleRates(lines ...*Line) error {
+	for i := 1; i < len(lines); i++ {
+		if expected, sr := lines[0].sampleRate(), lines[i].sampleRate(); sr != expected {
+			return fmt.Errorf("%w: line %d sample rate %v differs from line 0 sample rate %v", ErrInvalidProperties, i, sr, expected)
+		}
+	}
+	return nil
+}
+
+// forward returns output properties where optional properties that are
+// not set are taken from the input. Layout is forwarded only if number
+// of channels is the same. Length is scaled if sample rate has changed.
+func (p SignalProperties) forward(output SignalProperties) SignalProperties {
+	if output.Layout == UnknownLayout && output.Channels == p.Channels {
+		output.Layout = p.Layout
+	}
+	if output.BitDepth == 0 {
+		output.BitDepth = p.BitDepth
+	}
+	if output.Length == 0 && p.SampleRate > 0 {
+		output.Length = int(math.Round(float64(p.Length) * float64(output.SampleRate) / float64(p.SampleRate)))
+	}
+	if output.Metadata == nil && p.Metadata != nil {
+		// copy, so stages don't share the map.
+		output.Metadata = make(map[string]string, len(p.Metadata))
+		for k, v := range p.Metadata {
+			output.Metadata[k] = v
+		}
+	}
+	return output
+}
+
+// Check returns UnsupportedError if provided properties are not
+// accepted.
+func (a Accepts) Check(props SignalProperties) error {
+	if !a.acceptsSampleRate(props.SampleRate) || !a.acceptsChannels(props.Channels) {
+		return &UnsupportedError{
+			Properties: props,
+			Accepts:    a,
+		}
+	}
+	return nil
+}
+
+func (a Accepts) acceptsSampleRate(sampleRate signal.Frequency) bool {
+	if len(a.SampleRates) == 0 {
+		return true
+	}
+	for _, v := range a.SampleRates {
+		if v == sampleRate {
+			return true
+		}
+	}
+	return false
+}
+
+func (a Accepts) acceptsChannels(channels int) bool {
+	if len(a.Channels) == 0 {
+		return true
+	}
+	for _, v := range a.Channels {
+		if v == channels {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf("unsupported signal properties: %v Hz %d channels, accepted sample rates: %s, channels: %s",
+		e.Properties.SampleRate, e.Properties.Channels, anyIfEmpty(e.Accepts.SampleRates), anyIfEmpty(e.Accepts.Channels))
+}
+
+// anyIfEmpty formats set of accepted values.
+func anyIfEmpty(values interface{}) string {
+	if s := fmt.Sprint(values); s != "[]" {
+		return s
+	}
+	return "any"
+}
+
+// Accept returns allocator that checks input signal properties before
+// the processor allocation. If properties are not accepted, allocator
+// returns UnsupportedError.
+func (fn ProcessorAllocatorFunc) Accept(a Accepts) ProcessorAllocatorFunc {
+	return func(bufferSize int, props SignalProperties) (Processor, SignalProperties, error) {
+		if err := a.Check(props); err != nil {
+			return Processor{}, SignalProperties{}, err
+		}
+		return fn(bufferSize, props)
+	}
+}
+
+// Accept returns allocator that checks input signal properties before
+// the sink allocation. If properties are not accepted, allocator returns
+// UnsupportedError.
+func (fn SinkAllocatorFunc) Accept(a Accepts) SinkAllocatorFunc {
+	return func(bufferSize int, props SignalProperties) (Sink, error) {
+		if err := a.Check(props); err != nil {
+			return Sink{}, err
+		}
+		return fn(bufferSize, props)
+	}
+}
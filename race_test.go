@@ -0,0 +1,8 @@
+//go:build race
+// +build race
+
+package pipe_test
+
+// race is true if tests are executed with race detector. It's needed
+// because sync.Pool drops items randomly in race mode.
+const race = true
@@ -0,0 +1,155 @@
+package pipe
+
+import (
+	"context"
+	"fmt"
+
+	"pipelined.dev/signal"
+
+	"pipelined.dev/pipe/mutability"
+)
+
+// Reblock returns allocator of the processor that executes provided
+// processors with buffers of blockSize samples, independently from the
+// buffer size of the line. Input is accumulated and split into blocks
+// internally. Buffers of the line might be shorter than its buffer size,
+// so the signal is always delayed by blockSize samples and the processor
+// tail is increased by blockSize, so the last partial block is completed
+// with silence and pushed out. Latency and tails of nested processors are
+// added to the reblocking ones.
+//
+// Nested processors must support floating-point buffers and keep the
+// sample rate. They always receive float64 blocks, regardless of the
+// sample type of the line. Nested processors can't be bypassed, bypass
+// of the line applies to the reblocking processor as a whole. Their
+// mutations are delivered through the line as usual.
+func Reblock(blockSize int, processors ...ProcessorAllocatorFunc) ProcessorAllocatorFunc {
+	return func(bufferSize int, props SignalProperties) (Processor, SignalProperties, error) {
+		if blockSize < 1 {
+			return Processor{}, SignalProperties{}, fmt.Errorf("reblock: invalid block size %d", blockSize)
+		}
+		var (
+			chain  = make([]Processor, 0, len(processors))
+			blocks = []signal.Floating{block(props.Channels, blockSize)}
+			output = props
+			result Processor
+		)
+		for i, fn := range processors {
+			p, out, err := fn(blockSize, output)
+			if err != nil {
+				return Processor{}, SignalProperties{}, fmt.Errorf("reblock processor %d: %w", i, err)
+			}
+			switch {
+			case p.ProcessFunc == nil:
+				return Processor{}, SignalProperties{}, fmt.Errorf("reblock processor %d: floating-point buffers are not supported", i)
+			case p.Length != nil:
+				return Processor{}, SignalProperties{}, fmt.Errorf("reblock processor %d: output length must match input", i)
+			case p.Latency < 0:
+				return Processor{}, SignalProperties{}, fmt.Errorf("reblock processor %d: negative latency %d", i, p.Latency)
+			case p.Tail < 0:
+				return Processor{}, SignalProperties{}, fmt.Errorf("reblock processor %d: negative tail %d", i, p.Tail)
+			}
+			out = output.forward(out)
+			if err := out.validate(); err != nil {
+				return Processor{}, SignalProperties{}, fmt.Errorf("reblock processor %d: %w", i, err)
+			}
+			if out.SampleRate != output.SampleRate {
+				return Processor{}, SignalProperties{}, fmt.Errorf("reblock processor %d: sample rate %v->%v", i, output.SampleRate, out.SampleRate)
+			}
+			if p.Mutability != mutability.Immutable() {
+				result.nested = append(result.nested, p.Mutability)
+			}
+			result.Latency += p.Latency
+			result.Tail += p.Tail
+			chain = append(chain, p)
+			blocks = append(blocks, block(out.Channels, blockSize))
+			output = out
+		}
+		r := reblocker{
+			chain:  chain,
+			blocks: blocks,
+		}
+		result.ProcessFunc = r.process
+		result.Latency += blockSize
+		result.Tail += blockSize
+		result.FlushFunc = r.flush
+		return result, output, nil
+	}
+}
+
+func block(channels, size int) signal.Floating {
+	return signal.Allocator{
+		Channels: channels,
+		Length:   size,
+		Capacity: size,
+	}.Float64()
+}
+
+// reblocker executes the chain of processors with fixed-size blocks.
+// The first block is the input of the chain and the last one is its
+// output.
+type reblocker struct {
+	chain  []Processor
+	blocks []signal.Floating
+	// pos is the position in the blocks.
+	pos int
+}
+
+// process accumulates input into the block, the output is the result of
+// the previous block.
+func (r *reblocker) process(in, out signal.Floating) error {
+	input, output := r.blocks[0], r.blocks[len(r.blocks)-1]
+	for i := 0; i < in.Length(); {
+		n := in.Length() - i
+		if left := input.Length() - r.pos; left < n {
+			n = left
+		}
+		// output is read first, so empty chain delays the signal too.
+		copyBlock(out, i, output, r.pos, n)
+		copyBlock(input, r.pos, in, i, n)
+		i += n
+		if r.pos += n; r.pos == input.Length() {
+			r.pos = 0
+			if _, err := r.run(input.Length()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// run executes the chain with blocks of provided length and returns the
+// output block.
+func (r *reblocker) run(length int) (signal.Floating, error) {
+	for i := range r.chain {
+		in, out := r.blocks[i].Slice(0, length), r.blocks[i+1].Slice(0, length)
+		if err := r.chain[i].ProcessFunc(in, out); err != nil {
+			return nil, err
+		}
+	}
+	return r.blocks[len(r.blocks)-1].Slice(0, length), nil
+}
+
+// flush calls flush hooks of all nested processors, the first error is
+// returned.
+func (r *reblocker) flush(ctx context.Context) error {
+	var flushErr error
+	for i := range r.chain {
+		if fn := r.chain[i].FlushFunc; fn != nil {
+			if err := fn(ctx); err != nil && flushErr == nil {
+				flushErr = err
+			}
+		}
+	}
+	return flushErr
+}
+
+// copyBlock copies n samples of each channel from src at srcPos into dst
+// at dstPos.
+func copyBlock(dst signal.Floating, dstPos int, src signal.Floating, srcPos, n int) {
+	for c := 0; c < src.Channels(); c++ {
+		for i := 0; i < n; i++ {
+			dst.SetSample(dst.BufferIndex(c, dstPos+i), src.Sample(src.BufferIndex(c, srcPos+i)))
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package pipe
+
+import (
+	"context"
+	"io"
+
+	"pipelined.dev/pipe/internal/runner"
+	"pipelined.dev/pipe/mutability"
+)
+
+// Renderer executes the line synchronously on the caller's goroutine.
+// Each call pulls exactly one buffer from the source through processors
+// into the sink. No goroutines are started, so the results are
+// deterministic. It's useful for offline rendering, tests and hosts that
+// own the thread.
+type Renderer struct {
+	ctx       context.Context
+	line      runner.Line
+	mutations mutability.Mutations
+	err       error
+}
+
+// NewRenderer creates renderer for the line. Provided mutations will be
+// applied before the first buffer is rendered.
+func NewRenderer(ctx context.Context, l *Line, mutations ...mutability.Mutation) *Renderer {
+	r := Renderer{
+		ctx:  ctx,
+		line: l.runner(),
+	}
+	r.Push(mutations...)
+	return &r
+}
+
+// Push new mutations into renderer. They will be applied before the next
+// buffer is rendered.
+func (r *Renderer) Push(mutations ...mutability.Mutation) {
+	for _, m := range mutations {
+		r.mutations = r.mutations.Put(m)
+	}
+}
+
+// Next renders a single buffer. When source is done or error occurs,
+// flush hooks are called and the error is returned. If source is done,
+// io.EOF is returned. All subsequent calls return the same error.
+func (r *Renderer) Next() error {
+	if r.err != nil {
+		return r.err
+	}
+	err := r.ctx.Err()
+	if err == nil {
+		err = r.line.Step(r.mutations)
+		r.mutations = nil
+		if err == nil {
+			return nil
+		}
+	}
+	if flushErr := r.line.Flush(r.ctx); flushErr != nil && (err == io.EOF || err == r.ctx.Err()) {
+		err = flushErr
+	}
+	r.err = err
+	return err
+}
+
+// Render renders n buffers. It stops at the first error, see Next for
+// details.
+func (r *Renderer) Render(n int) error {
+	for i := 0; i < n; i++ {
+		if err := r.Next(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package pipe
+
+import (
+	"fmt"
+
+	"pipelined.dev/signal"
+
+	"pipelined.dev/pipe/internal/runner"
+)
+
+// SampleType is the type of samples in the line buffers. Components
+// receive buffers of the line sample type if they provide functions
+// for it: SourceFunc, ProcessFunc and SinkFunc for floating-point types
+// and SignedSourceFunc, SignedProcessFunc and SignedSinkFunc for the
+// fixed-point one. Otherwise they receive float64 or int32 buffers and
+// sample converters are inserted at the boundaries.
+type SampleType uint8
+
+// Supported sample types. Int32 buffers have 32 bits depth.
+const (
+	Float64 SampleType = iota
+	Float32
+	Int32
+)
+
+// String returns the name of the sample type.
+func (t SampleType) String() string {
+	return t.runner().String()
+}
+
+func (t SampleType) runner() runner.SampleType {
+	switch t {
+	case Float32:
+		return runner.Float32
+	case Int32:
+		return runner.Int32
+	}
+	return runner.Float64
+}
+
+// choose returns the sample type of component buffers. Line sample
+// type is used if component supports it, otherwise the default type of
+// the supported kind is used.
+func (t SampleType) choose(floating, signed bool) SampleType {
+	switch {
+	case t.runner().Floating() && floating, !t.runner().Floating() && signed:
+		return t
+	case floating:
+		return Float64
+	case signed:
+		return Int32
+	}
+	return t
+}
+
+// floating returns the floating-point sample type closest to t.
+func (t SampleType) floating() SampleType {
+	if t.runner().Floating() {
+		return t
+	}
+	return Float64
+}
+
+// convertSamples appends sample type converter if the line output has
+// different sample type.
+func (l *Line) convertSamples(bufferSize int, props SignalProperties, to SampleType) {
+	from := l.stages[len(l.stages)-1].SampleType
+	if from == to {
+		return
+	}
+	pool := signal.GetPoolAllocator(props.Channels, bufferSize, bufferSize)
+	l.processors = append(l.processors, runner.Processor{
+		InPool:  pool,
+		OutPool: pool,
+		InType:  from.runner(),
+		OutType: to.runner(),
+		Fn: func(in, out signal.Signal) error {
+			runner.Copy(in, out)
+			return nil
+		},
+	})
+	l.stages = append(l.stages, Stage{
+		Name:       fmt.Sprintf("sample converter %v->%v", from, to),
+		BufferSize: bufferSize,
+		Properties: props,
+		SampleType: to,
+	})
+}
@@ -0,0 +1,113 @@
+package pipe
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"pipelined.dev/pipe/internal/runner"
+	"pipelined.dev/pipe/mutability"
+)
+
+// scheduler executes lines on a bounded pool of workers. Workers are
+// started on demand when lines are queued, up to the limit, and exit
+// when the queue is empty. Each time a line is picked up by a worker,
+// its sink pulls exactly one buffer through processors from the source.
+// After that the line is put back to the queue, so lines are served in
+// round-robin order.
+type scheduler struct {
+	workers int
+	mu      sync.Mutex
+	running int
+	queue   []*task
+}
+
+// task is a line scheduled for execution.
+type task struct {
+	ctx      context.Context
+	line     runner.Line
+	mutators chan mutability.Mutations
+	errs     chan error
+}
+
+// schedule adds the line to the queue. Returned channel receives the
+// first error of the line and closed when the line is done.
+func (s *scheduler) schedule(ctx context.Context, l *Line) <-chan error {
+	t := task{
+		ctx:      ctx,
+		line:     l.runner(),
+		mutators: l.mutators,
+		errs:     make(chan error, 1),
+	}
+	s.put(&t)
+	return t.errs
+}
+
+// put the task to the queue and start a new worker if limit isn't
+// reached yet.
+func (s *scheduler) put(t *task) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queue = append(s.queue, t)
+	if s.running < s.workers {
+		s.running++
+		go s.work()
+	}
+}
+
+// next returns the next task from the queue. If queue is empty, nil is
+// returned and the calling worker must stop.
+func (s *scheduler) next() *task {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.queue) == 0 {
+		s.running--
+		return nil
+	}
+	t := s.queue[0]
+	s.queue[0] = nil
+	s.queue = s.queue[1:]
+	return t
+}
+
+// work executes tasks until the queue is empty.
+func (s *scheduler) work() {
+	for t := s.next(); t != nil; t = s.next() {
+		if t.step() {
+			s.put(t)
+		}
+	}
+}
+
+// step executes a single buffer of the line. Returns false if line is
+// done.
+func (t *task) step() bool {
+	var mutations mutability.Mutations
+	select {
+	case mutations = <-t.mutators:
+	case <-t.ctx.Done():
+		t.done(nil)
+		return false
+	default:
+	}
+
+	if err := t.line.Step(mutations); err != nil {
+		if err == io.EOF {
+			err = nil
+		}
+		t.done(err)
+		return false
+	}
+	return true
+}
+
+// done flushes the line and reports the first error.
+func (t *task) done(err error) {
+	defer close(t.errs)
+	if flushErr := t.line.Flush(t.ctx); err == nil {
+		err = flushErr
+	}
+	if err != nil {
+		t.errs <- err
+	}
+}
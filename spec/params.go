@@ -0,0 +1,76 @@
+package spec
+
+import (
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Params are parameters of the component. Factories read them with
+// typed getters, defaults are returned for missing parameters. Loader
+// returns error if factory didn't read some of the parameters.
+type Params struct {
+	node   *yaml.Node
+	values map[string]*yaml.Node
+	used   map[string]bool
+}
+
+// Float returns the floating-point parameter.
+func (p *Params) Float(name string, def float64) (float64, error) {
+	err := p.decode(name, &def)
+	return def, err
+}
+
+// Int returns the integer parameter.
+func (p *Params) Int(name string, def int) (int, error) {
+	err := p.decode(name, &def)
+	return def, err
+}
+
+// String returns the string parameter.
+func (p *Params) String(name string, def string) (string, error) {
+	err := p.decode(name, &def)
+	return def, err
+}
+
+// Bool returns the boolean parameter.
+func (p *Params) Bool(name string, def bool) (bool, error) {
+	err := p.decode(name, &def)
+	return def, err
+}
+
+// decode decodes parameter into provided value if it's defined.
+func (p *Params) decode(name string, v interface{}) error {
+	p.used[name] = true
+	node, ok := p.values[name]
+	if !ok {
+		return nil
+	}
+	return decode(node, v)
+}
+
+// unused returns error for the first parameter that wasn't read.
+func (p *Params) unused() error {
+	var names []string
+	for name := range p.values {
+		if !p.used[name] {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Strings(names)
+	// report the key position.
+	for i := 0; i < len(p.node.Content); i += 2 {
+		if p.node.Content[i].Value == "params" {
+			params := p.node.Content[i+1]
+			for j := 0; j < len(params.Content); j += 2 {
+				if params.Content[j].Value == names[0] {
+					return errorf(params.Content[j], "unknown parameter %q", names[0])
+				}
+			}
+		}
+	}
+	return errorf(p.node, "unknown parameter %q", names[0])
+}
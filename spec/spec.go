@@ -0,0 +1,307 @@
+// Package spec allows to define pipelines in YAML or JSON documents.
+//
+// Components are created by factories registered in the Registry under
+// type names. Document defines the buffer size and lines, each line has
+// a source, optional processors and a sink. Components have a type and
+// optional parameters, which are passed to the factory:
+//
+//	bufferSize: 512
+//	lines:
+//	  - source:
+//	      type: wav
+//	      params:
+//	        path: input.wav
+//	    processors:
+//	      - type: gain
+//	        params:
+//	          value: 0.5
+//	    sink:
+//	      type: wav
+//	      params:
+//	        path: output.wav
+//
+// Since JSON is a subset of YAML, the same schema works for JSON
+// documents. All errors contain the line and column of the document.
+package spec
+
+import (
+	"errors"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+
+	"pipelined.dev/signal"
+
+	"pipelined.dev/pipe"
+)
+
+type (
+	// SourceFactory creates source allocator from parameters.
+	SourceFactory func(*Params) (pipe.SourceAllocatorFunc, error)
+
+	// ProcessorFactory creates processor allocator from parameters.
+	ProcessorFactory func(*Params) (pipe.ProcessorAllocatorFunc, error)
+
+	// SinkFactory creates sink allocator from parameters.
+	SinkFactory func(*Params) (pipe.SinkAllocatorFunc, error)
+
+	// Registry maps type names to component factories.
+	Registry struct {
+		sources    map[string]SourceFactory
+		processors map[string]ProcessorFactory
+		sinks      map[string]SinkFactory
+	}
+
+	// Spec is the loaded pipeline specification.
+	Spec struct {
+		BufferSize int
+		Routes     []pipe.Routing
+	}
+
+	// Error is the error in the document.
+	Error struct {
+		Line   int
+		Column int
+		Err    error
+	}
+)
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		sources:    make(map[string]SourceFactory),
+		processors: make(map[string]ProcessorFactory),
+		sinks:      make(map[string]SinkFactory),
+	}
+}
+
+// Source registers source factory under provided type name.
+func (r *Registry) Source(name string, fn SourceFactory) {
+	r.sources[name] = fn
+}
+
+// Processor registers processor factory under provided type name.
+func (r *Registry) Processor(name string, fn ProcessorFactory) {
+	r.processors[name] = fn
+}
+
+// Sink registers sink factory under provided type name.
+func (r *Registry) Sink(name string, fn SinkFactory) {
+	r.sinks[name] = fn
+}
+
+// Load parses YAML or JSON document and creates routes with registered
+// factories. Document is validated against the schema, the *Error is
+// returned if it's not valid.
+func (r *Registry) Load(data []byte) (*Spec, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("error parsing spec: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, &Error{Line: doc.Line, Column: doc.Column, Err: fmt.Errorf("empty document")}
+	}
+	fields, err := mapping(doc.Content[0], "bufferSize", "lines")
+	if err != nil {
+		return nil, err
+	}
+
+	var spec Spec
+	bufferSize, ok := fields["bufferSize"]
+	if !ok {
+		return nil, errorf(doc.Content[0], "bufferSize is required")
+	}
+	if err := decode(bufferSize, &spec.BufferSize); err != nil {
+		return nil, err
+	}
+	if spec.BufferSize <= 0 {
+		return nil, errorf(bufferSize, "bufferSize must be positive")
+	}
+
+	lines, ok := fields["lines"]
+	if !ok {
+		return nil, errorf(doc.Content[0], "lines are required")
+	}
+	if lines.Kind != yaml.SequenceNode || len(lines.Content) == 0 {
+		return nil, errorf(lines, "lines must be a non-empty sequence")
+	}
+	for _, line := range lines.Content {
+		route, err := r.route(line)
+		if err != nil {
+			return nil, err
+		}
+		spec.Routes = append(spec.Routes, route)
+	}
+	return &spec, nil
+}
+
+// Lines binds routes of the spec into lines. Lines are run in a single
+// pipe, so their sinks must have the same sample rate.
+func (s *Spec) Lines(options ...pipe.LineOption) ([]*pipe.Line, error) {
+	var lines []*pipe.Line
+	for i := range s.Routes {
+		l, err := s.Routes[i].Line(s.BufferSize, options...)
+		if err != nil {
+			return nil, fmt.Errorf("error binding line %d: %w", i, err)
+		}
+		if i > 0 {
+			if expected, sr := sampleRate(lines[0]), sampleRate(l); sr != expected {
+				return nil, fmt.Errorf("error binding line %d: %w: sample rate %v differs from line 0 sample rate %v", i, pipe.ErrInvalidProperties, sr, expected)
+			}
+		}
+		lines = append(lines, l)
+	}
+	return lines, nil
+}
+
+// sampleRate returns the sample rate of the line sink.
+func sampleRate(l *pipe.Line) signal.Frequency {
+	stages := l.Stages()
+	return stages[len(stages)-1].Properties.SampleRate
+}
+
+// route creates routing for the line node.
+func (r *Registry) route(node *yaml.Node) (pipe.Routing, error) {
+	fields, err := mapping(node, "source", "processors", "sink")
+	if err != nil {
+		return pipe.Routing{}, err
+	}
+	var route pipe.Routing
+
+	source, ok := fields["source"]
+	if !ok {
+		return pipe.Routing{}, errorf(node, "source is required")
+	}
+	if err := component(source, r.sources, func(fn SourceFactory, p *Params) (err error) {
+		route.Source, err = fn(p)
+		return
+	}); err != nil {
+		return pipe.Routing{}, err
+	}
+
+	if processors, ok := fields["processors"]; ok {
+		if processors.Kind != yaml.SequenceNode {
+			return pipe.Routing{}, errorf(processors, "processors must be a sequence")
+		}
+		for _, processor := range processors.Content {
+			if err := component(processor, r.processors, func(fn ProcessorFactory, p *Params) error {
+				allocator, err := fn(p)
+				route.Processors = append(route.Processors, allocator)
+				return err
+			}); err != nil {
+				return pipe.Routing{}, err
+			}
+		}
+	}
+
+	sink, ok := fields["sink"]
+	if !ok {
+		return pipe.Routing{}, errorf(node, "sink is required")
+	}
+	if err := component(sink, r.sinks, func(fn SinkFactory, p *Params) (err error) {
+		route.Sink, err = fn(p)
+		return
+	}); err != nil {
+		return pipe.Routing{}, err
+	}
+	return route, nil
+}
+
+// component looks up the factory for the component node and calls
+// provided function with it. All parameters must be used by the
+// factory.
+func component[F any](node *yaml.Node, factories map[string]F, create func(F, *Params) error) error {
+	fields, err := mapping(node, "type", "params")
+	if err != nil {
+		return err
+	}
+	typeNode, ok := fields["type"]
+	if !ok {
+		return errorf(node, "type is required")
+	}
+	var name string
+	if err := decode(typeNode, &name); err != nil {
+		return err
+	}
+	factory, ok := factories[name]
+	if !ok {
+		return errorf(typeNode, "unknown type %q", name)
+	}
+
+	params := Params{
+		node:   node,
+		values: make(map[string]*yaml.Node),
+		used:   make(map[string]bool),
+	}
+	if paramsNode, ok := fields["params"]; ok {
+		if params.values, err = mapping(paramsNode); err != nil {
+			return err
+		}
+	}
+	if err := create(factory, &params); err != nil {
+		var specErr *Error
+		if errors.As(err, &specErr) {
+			return err
+		}
+		return errorf(node, "%s: %w", name, err)
+	}
+	return params.unused()
+}
+
+// mapping returns the fields of the mapping node. If keys are provided,
+// other keys are not allowed.
+func mapping(node *yaml.Node, keys ...string) (map[string]*yaml.Node, error) {
+	if node.Kind != yaml.MappingNode {
+		return nil, errorf(node, "mapping expected")
+	}
+	fields := make(map[string]*yaml.Node)
+	for i := 0; i < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if len(keys) > 0 && !contains(keys, key.Value) {
+			return nil, errorf(key, "unknown field %q", key.Value)
+		}
+		if _, ok := fields[key.Value]; ok {
+			return nil, errorf(key, "duplicate field %q", key.Value)
+		}
+		fields[key.Value] = value
+	}
+	return fields, nil
+}
+
+func contains(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// decode decodes scalar node into provided value.
+func decode(node *yaml.Node, v interface{}) error {
+	if node.Kind != yaml.ScalarNode {
+		return errorf(node, "scalar expected")
+	}
+	if err := node.Decode(v); err != nil {
+		return errorf(node, "invalid value %q", node.Value)
+	}
+	return nil
+}
+
+func errorf(node *yaml.Node, format string, args ...interface{}) *Error {
+	return &Error{
+		Line:   node.Line,
+		Column: node.Column,
+		Err:    fmt.Errorf(format, args...),
+	}
+}
+
+// Error returns the error message with position in the document.
+func (e *Error) Error() string {
+	return fmt.Sprintf("line %d, column %d: %v", e.Line, e.Column, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,205 @@
+package spec_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"pipelined.dev/signal"
+
+	"pipelined.dev/pipe"
+	"pipelined.dev/pipe/mock"
+	"pipelined.dev/pipe/spec"
+)
+
+func registry(sink *mock.Sink) *spec.Registry {
+	r := spec.NewRegistry()
+	r.Source("mock", func(p *spec.Params) (pipe.SourceAllocatorFunc, error) {
+		limit, err := p.Int("limit", 0)
+		if err != nil {
+			return nil, err
+		}
+		channels, err := p.Int("channels", 1)
+		if err != nil {
+			return nil, err
+		}
+		value, err := p.Float("value", 0)
+		if err != nil {
+			return nil, err
+		}
+		sampleRate, err := p.Int("sampleRate", 44100)
+		if err != nil {
+			return nil, err
+		}
+		return (&mock.Source{
+			Limit:      limit,
+			Channels:   channels,
+			Value:      value,
+			SampleRate: signal.Frequency(sampleRate),
+		}).Source(), nil
+	})
+	r.Processor("mock", func(p *spec.Params) (pipe.ProcessorAllocatorFunc, error) {
+		return (&mock.Processor{}).Processor(), nil
+	})
+	r.Sink("mock", func(p *spec.Params) (pipe.SinkAllocatorFunc, error) {
+		discard, err := p.Bool("discard", false)
+		if err != nil {
+			return nil, err
+		}
+		if discard {
+			return nil, errors.New("discard is not supported")
+		}
+		return sink.Sink(), nil
+	})
+	return r
+}
+
+func TestLoad(t *testing.T) {
+	testLoad := func(doc string) func(*testing.T) {
+		return func(t *testing.T) {
+			t.Helper()
+			sink := &mock.Sink{}
+			s, err := registry(sink).Load([]byte(doc))
+			assertEqual(t, "error", err, nil)
+			assertEqual(t, "buffer size", s.BufferSize, 256)
+			assertEqual(t, "routes", len(s.Routes), 1)
+			assertEqual(t, "processors", len(s.Routes[0].Processors), 2)
+
+			lines, err := s.Lines()
+			assertEqual(t, "error", err, nil)
+			err = pipe.New(context.Background(), pipe.WithLines(lines...)).Wait()
+			assertEqual(t, "error", err, nil)
+			assertEqual(t, "samples", sink.Samples, 1000)
+			assertEqual(t, "channels", sink.Values.Channels(), 2)
+			assertEqual(t, "value", sink.Values.Sample(0), 0.5)
+		}
+	}
+	t.Run("yaml", testLoad(`
+bufferSize: 256
+lines:
+  - source:
+      type: mock
+      params:
+        limit: 1000
+        channels: 2
+        value: 0.5
+    processors:
+      - type: mock
+      - type: mock
+    sink:
+      type: mock
+`))
+	t.Run("json", testLoad(`{
+	"bufferSize": 256,
+	"lines": [{
+		"source": {"type": "mock", "params": {"limit": 1000, "channels": 2, "value": 0.5}},
+		"processors": [{"type": "mock"}, {"type": "mock"}],
+		"sink": {"type": "mock"}
+	}]
+}`))
+}
+
+func TestLinesSampleRate(t *testing.T) {
+	s, err := registry(&mock.Sink{}).Load([]byte(`
+bufferSize: 256
+lines:
+  - source:
+      type: mock
+    sink:
+      type: mock
+  - source:
+      type: mock
+      params:
+        sampleRate: 48000
+    sink:
+      type: mock
+`))
+	assertEqual(t, "load error", err, nil)
+	_, err = s.Lines()
+	assertEqual(t, "sample rate error", errors.Is(err, pipe.ErrInvalidProperties), true)
+}
+
+func TestLoadErrors(t *testing.T) {
+	testError := func(doc string, line, column int, message string) func(*testing.T) {
+		return func(t *testing.T) {
+			t.Helper()
+			_, err := registry(&mock.Sink{}).Load([]byte(doc))
+			var specErr *spec.Error
+			assertEqual(t, "spec error", errors.As(err, &specErr), true)
+			assertEqual(t, "line", specErr.Line, line)
+			assertEqual(t, "column", specErr.Column, column)
+			assertEqual(t, "message", specErr.Err.Error(), message)
+		}
+	}
+	t.Run("buffer size", testError(`
+bufferSize: -1
+lines: []
+`, 2, 13, "bufferSize must be positive"))
+	t.Run("buffer size type", testError(`
+bufferSize: large
+`, 2, 13, `invalid value "large"`))
+	t.Run("no lines", testError(`
+bufferSize: 512
+lines: []
+`, 3, 8, "lines must be a non-empty sequence"))
+	t.Run("unknown field", testError(`
+bufferSize: 512
+lines:
+  - source:
+      type: mock
+    sinks:
+      type: mock
+`, 6, 5, `unknown field "sinks"`))
+	t.Run("missing sink", testError(`
+bufferSize: 512
+lines:
+  - source:
+      type: mock
+`, 4, 5, "sink is required"))
+	t.Run("unknown type", testError(`
+bufferSize: 512
+lines:
+  - source:
+      type: generator
+    sink:
+      type: mock
+`, 5, 13, `unknown type "generator"`))
+	t.Run("invalid param", testError(`
+bufferSize: 512
+lines:
+  - source:
+      type: mock
+      params:
+        limit: all
+    sink:
+      type: mock
+`, 7, 16, `invalid value "all"`))
+	t.Run("unknown param", testError(`
+bufferSize: 512
+lines:
+  - source:
+      type: mock
+      params:
+        length: 10
+    sink:
+      type: mock
+`, 7, 9, `unknown parameter "length"`))
+	t.Run("factory error", testError(`
+bufferSize: 512
+lines:
+  - source:
+      type: mock
+    sink:
+      type: mock
+      params:
+        discard: true
+`, 7, 7, "mock: discard is not supported"))
+}
+
+func assertEqual(t *testing.T, name string, result, expected interface{}) {
+	t.Helper()
+	if !reflect.DeepEqual(expected, result) {
+		t.Fatalf("%v\nresult: \t%T\t%+v \nexpected: \t%T\t%+v", name, result, result, expected, expected)
+	}
+}
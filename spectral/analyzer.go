@@ -0,0 +1,150 @@
+package spectral
+
+import (
+	"math"
+	"math/cmplx"
+	"sync/atomic"
+
+	"pipelined.dev/signal"
+
+	"pipelined.dev/pipe"
+)
+
+// Spectrum is the magnitude spectrum of the frame. BinWidth is the
+// frequency distance between bins in Hz. Magnitudes are in dB relative
+// to the full scale sine and averaged over channels by power.
+type Spectrum struct {
+	BinWidth   float64
+	Magnitudes []float64
+}
+
+// Analyzer is the sink that measures the spectrum of the signal. Size,
+// Hop and Window have the same meaning as for STFT. Reading is updated
+// after each frame. It can be observed with Snapshot method from any
+// goroutine or received from the Readings channel. Readings are sent
+// without blocking, so they are dropped if the channel is not ready.
+// Analyzer doesn't lock or allocate while the pipe runs, so spectrums
+// received from the channel are reused: a reading is valid until the
+// next one is received.
+type Analyzer struct {
+	Size     int
+	Hop      int
+	Window   Window
+	Readings chan<- Spectrum
+
+	state atomic.Value
+}
+
+// analyzerState holds the reading of a single allocation. Magnitudes
+// are stored as float64 bits, so they can be read while the runner
+// updates them. Readings sent into the channel are rotated, the reading
+// that is updated is never the one held by the receiver or buffered in
+// the channel.
+type analyzerState struct {
+	binWidth   float64
+	magnitudes []uint64
+	readings   []Spectrum
+	next       int
+}
+
+// Snapshot returns the last reading.
+func (a *Analyzer) Snapshot() Spectrum {
+	s, ok := a.state.Load().(*analyzerState)
+	if !ok {
+		return Spectrum{}
+	}
+	reading := Spectrum{
+		BinWidth:   s.binWidth,
+		Magnitudes: make([]float64, len(s.magnitudes)),
+	}
+	for k := range s.magnitudes {
+		reading.Magnitudes[k] = math.Float64frombits(atomic.LoadUint64(&s.magnitudes[k]))
+	}
+	return reading
+}
+
+// Sink returns allocator of the analyzer sink.
+func (a *Analyzer) Sink() pipe.SinkAllocatorFunc {
+	return func(bufferSize int, props pipe.SignalProperties) (pipe.Sink, error) {
+		f, err := newFramer(a.Size, a.Hop, a.Window, props.Channels)
+		if err != nil {
+			return pipe.Sink{}, err
+		}
+		// scale converts power of the bin into full scale sine power.
+		var gain float64
+		for _, v := range f.window {
+			gain += v
+		}
+		scale := 4 / (gain * gain)
+		power := make([]float64, f.size/2+1)
+		s := a.reset(float64(props.SampleRate)/float64(f.size), len(power))
+		return pipe.Sink{
+			SinkFunc: func(in signal.Floating) error {
+				for i := 0; i < in.Length(); i++ {
+					for c := range f.input {
+						f.input[c][f.pos] = in.Sample(in.BufferIndex(c, i))
+					}
+					if !f.next() {
+						continue
+					}
+					for k := range power {
+						power[k] = 0
+					}
+					for c := range f.input {
+						for k, v := range f.analyze(c) {
+							abs := cmplx.Abs(v)
+							power[k] += abs * abs
+						}
+					}
+					f.shift()
+					s.publish(a.Readings, power, scale/float64(len(f.input)))
+				}
+				return nil
+			},
+		}, nil
+	}
+}
+
+// reset initializes the reading for provided number of bins and
+// capacity of the readings channel.
+func (a *Analyzer) reset(binWidth float64, bins int) *analyzerState {
+	s := analyzerState{
+		binWidth:   binWidth,
+		magnitudes: make([]uint64, bins),
+	}
+	silence := math.Float64bits(math.Inf(-1))
+	for k := range s.magnitudes {
+		s.magnitudes[k] = silence
+	}
+	if a.Readings != nil {
+		s.readings = make([]Spectrum, cap(a.Readings)+2)
+		for i := range s.readings {
+			s.readings[i] = Spectrum{
+				BinWidth:   binWidth,
+				Magnitudes: make([]float64, bins),
+			}
+		}
+	}
+	a.state.Store(&s)
+	return &s
+}
+
+// publish updates the reading with scaled power of bins and sends it
+// into the channel if it's ready.
+func (s *analyzerState) publish(readings chan<- Spectrum, power []float64, scale float64) {
+	for k, p := range power {
+		atomic.StoreUint64(&s.magnitudes[k], math.Float64bits(10*math.Log10(p*scale)))
+	}
+	if readings == nil {
+		return
+	}
+	reading := s.readings[s.next]
+	for k := range s.magnitudes {
+		reading.Magnitudes[k] = math.Float64frombits(s.magnitudes[k])
+	}
+	select {
+	case readings <- reading:
+		s.next = (s.next + 1) % len(s.readings)
+	default:
+	}
+}
@@ -0,0 +1,69 @@
+package spectral_test
+
+import (
+	"context"
+	"fmt"
+	"math/cmplx"
+
+	"pipelined.dev/pipe"
+	"pipelined.dev/pipe/generator"
+	"pipelined.dev/pipe/meter"
+	"pipelined.dev/pipe/spectral"
+)
+
+// gate returns the spectral gate that removes bins with magnitude below
+// the threshold.
+func gate(threshold float64) *spectral.STFT {
+	return &spectral.STFT{
+		Size:   1024,
+		Window: spectral.Hann,
+		Frame: func(_ int, spectrum []complex128) {
+			for k := range spectrum {
+				if cmplx.Abs(spectrum[k]) < threshold {
+					spectrum[k] = 0
+				}
+			}
+		},
+	}
+}
+
+func Example_gate() {
+	for _, g := range []*generator.Generator{
+		{
+			Waveform:   generator.Sine,
+			Frequency:  1000,
+			Amplitude:  0.5,
+			Channels:   1,
+			SampleRate: 44100,
+			Length:     44100,
+		},
+		{
+			Waveform:   generator.WhiteNoise,
+			Amplitude:  0.01,
+			Channels:   1,
+			SampleRate: 44100,
+			Length:     44100,
+			Seed:       1,
+		},
+	} {
+		peak := &meter.Peak{}
+		line, err := pipe.Routing{
+			Source:     g.Source(),
+			Processors: pipe.Processors(gate(1).Processor()),
+			Sink:       peak.Sink(),
+		}.Line(512)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		if err := pipe.New(context.Background(), pipe.WithLines(line)).Wait(); err != nil {
+			fmt.Println(err)
+			return
+		}
+		fmt.Printf("%v: %.0f dB\n", g.Waveform, peak.Snapshot().Max[0])
+	}
+
+	// Output:
+	// sine: -6 dB
+	// white: -Inf dB
+}
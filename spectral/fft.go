@@ -0,0 +1,84 @@
+package spectral
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"math/cmplx"
+)
+
+// FFT computes the discrete Fourier transform of complex sequences with
+// radix-2 algorithm. Size must be a power of two. Twiddle factors are
+// precomputed, so transforms don't allocate.
+type FFT struct {
+	size     int
+	twiddles []complex128
+	reversed []int
+}
+
+// NewFFT returns FFT of provided size.
+func NewFFT(size int) (*FFT, error) {
+	if size < 1 || size&(size-1) != 0 {
+		return nil, fmt.Errorf("fft size %d is not a power of two", size)
+	}
+	f := FFT{
+		size:     size,
+		twiddles: make([]complex128, size/2),
+		reversed: make([]int, size),
+	}
+	for i := range f.twiddles {
+		f.twiddles[i] = cmplx.Rect(1, -2*math.Pi*float64(i)/float64(size))
+	}
+	shift := 64 - bits.Len(uint(size-1))
+	for i := range f.reversed {
+		f.reversed[i] = int(bits.Reverse64(uint64(i)) >> shift)
+	}
+	return &f, nil
+}
+
+// Size returns the size of the transform.
+func (f *FFT) Size() int {
+	return f.size
+}
+
+// Transform computes the forward transform in place. Length of x must
+// be equal to the size of the transform.
+func (f *FFT) Transform(x []complex128) {
+	f.transform(x, false)
+}
+
+// Inverse computes the inverse transform in place. Result is scaled by
+// 1/size, so the inverse of the forward transform returns the original
+// sequence.
+func (f *FFT) Inverse(x []complex128) {
+	f.transform(x, true)
+	scale := complex(1/float64(f.size), 0)
+	for i := range x {
+		x[i] *= scale
+	}
+}
+
+func (f *FFT) transform(x []complex128, inverse bool) {
+	if len(x) != f.size {
+		panic(fmt.Sprintf("fft size %d doesn't match the input length %d", f.size, len(x)))
+	}
+	for i, j := range f.reversed {
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+	for length := 2; length <= f.size; length <<= 1 {
+		half := length / 2
+		step := f.size / length
+		for start := 0; start < f.size; start += length {
+			for k := 0; k < half; k++ {
+				w := f.twiddles[k*step]
+				if inverse {
+					w = cmplx.Conj(w)
+				}
+				a, b := x[start+k], x[start+k+half]*w
+				x[start+k], x[start+k+half] = a+b, a-b
+			}
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package spectral_test
+
+import (
+	"context"
+	"io"
+	"math"
+	"math/cmplx"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"pipelined.dev/signal"
+
+	"pipelined.dev/pipe"
+	"pipelined.dev/pipe/generator"
+	"pipelined.dev/pipe/mock"
+	"pipelined.dev/pipe/spectral"
+)
+
+const bufferSize = 512
+
+func TestFFT(t *testing.T) {
+	const size = 64
+	f, err := spectral.NewFFT(size)
+	assertEqual(t, "error", err, nil)
+	assertEqual(t, "size", f.Size(), size)
+
+	r := rand.New(rand.NewSource(1))
+	x := make([]complex128, size)
+	for i := range x {
+		x[i] = complex(r.Float64()-0.5, r.Float64()-0.5)
+	}
+	// naive discrete Fourier transform.
+	expected := make([]complex128, size)
+	for k := range expected {
+		for n, v := range x {
+			expected[k] += v * cmplx.Rect(1, -2*math.Pi*float64(k*n)/size)
+		}
+	}
+	result := append([]complex128(nil), x...)
+	f.Transform(result)
+	for k := range expected {
+		assertNear(t, "transform", cmplx.Abs(result[k]-expected[k]), 0, 1e-9)
+	}
+	f.Inverse(result)
+	for n := range x {
+		assertNear(t, "inverse", cmplx.Abs(result[n]-x[n]), 0, 1e-12)
+	}
+
+	_, err = spectral.NewFFT(100)
+	assertEqual(t, "size error", err != nil, true)
+}
+
+// noise returns the white noise generator.
+func noise(length int) *generator.Generator {
+	return &generator.Generator{
+		Waveform:   generator.WhiteNoise,
+		Amplitude:  0.5,
+		Channels:   2,
+		SampleRate: 44100,
+		Length:     length,
+		Seed:       1,
+	}
+}
+
+// render returns the sink values of the line.
+func render(t *testing.T, source pipe.SourceAllocatorFunc, processors ...pipe.ProcessorAllocatorFunc) (*pipe.Line, *mock.Sink) {
+	t.Helper()
+	sink := &mock.Sink{}
+	line, err := pipe.Routing{
+		Source:     source,
+		Processors: processors,
+		Sink:       sink.Sink(),
+	}.Line(bufferSize)
+	assertEqual(t, "line error", err, nil)
+	err = pipe.NewRenderer(context.Background(), line).Render(1000)
+	assertEqual(t, "render error", err, io.EOF)
+	return line, sink
+}
+
+func TestSTFT(t *testing.T) {
+	const length = 5000
+	_, reference := render(t, noise(length).Source())
+	testIdentity := func(size, hop int, window spectral.Window) func(*testing.T) {
+		return func(t *testing.T) {
+			t.Helper()
+			var frames int
+			stft := &spectral.STFT{
+				Size:   size,
+				Hop:    hop,
+				Window: window,
+				Frame: func(channel int, spectrum []complex128) {
+					assertEqual(t, "bins", len(spectrum), size/2+1)
+					frames++
+				},
+			}
+			line, sink := render(t, noise(length).Source(), stft.Processor())
+			if hop == 0 {
+				hop = size / 4
+			}
+			latency := size
+			assertEqual(t, "latency", line.Latency(), latency)
+			assertEqual(t, "frames", frames, 2*((length+latency)/hop))
+			assertEqual(t, "samples", sink.Counter.Samples, length+latency)
+			for i := 0; i < reference.Values.Len(); i++ {
+				assertNear(t, "value", sink.Values.Sample(i+latency*2), reference.Values.Sample(i), 1e-9)
+			}
+		}
+	}
+	t.Run("hann", testIdentity(1024, 0, spectral.Hann))
+	t.Run("hann half overlap", testIdentity(256, 128, spectral.Hann))
+	t.Run("hamming", testIdentity(512, 128, spectral.Hamming))
+	t.Run("blackman", testIdentity(2048, 256, spectral.Blackman))
+	t.Run("hop exceeds buffer", testIdentity(4096, 1024, spectral.Hann))
+
+	t.Run("zero spectrum", func(t *testing.T) {
+		stft := &spectral.STFT{
+			Size: 256,
+			Frame: func(_ int, spectrum []complex128) {
+				for k := range spectrum {
+					spectrum[k] = 0
+				}
+			},
+		}
+		_, sink := render(t, noise(length).Source(), stft.Processor())
+		for i := 0; i < sink.Values.Len(); i++ {
+			assertNear(t, "value", sink.Values.Sample(i), 0, 1e-12)
+		}
+	})
+}
+
+func TestAnalyzer(t *testing.T) {
+	readings := make(chan spectral.Spectrum, 1)
+	a := &spectral.Analyzer{
+		Size:     1024,
+		Window:   spectral.Hann,
+		Readings: readings,
+	}
+	// sine at the center of the bin 64.
+	sine := &generator.Generator{
+		Waveform:   generator.Sine,
+		Frequency:  500,
+		Amplitude:  0.5,
+		Channels:   2,
+		SampleRate: 8000,
+		Length:     8000,
+	}
+	line, err := pipe.Routing{
+		Source: sine.Source(),
+		Sink:   a.Sink(),
+	}.Line(bufferSize)
+	assertEqual(t, "line error", err, nil)
+	err = pipe.New(context.Background(), pipe.WithLines(line)).Wait()
+	assertEqual(t, "run error", err, nil)
+
+	reading := a.Snapshot()
+	assertEqual(t, "bin width", reading.BinWidth, 7.8125)
+	assertEqual(t, "bins", len(reading.Magnitudes), 513)
+	assertNear(t, "peak", reading.Magnitudes[64], -6.0206, 1e-6)
+	assertEqual(t, "leakage", reading.Magnitudes[100] < -100, true)
+	assertEqual(t, "first reading", len((<-readings).Magnitudes), 513)
+}
+
+func TestAnalyzerReadings(t *testing.T) {
+	const size = 64
+	readings := make(chan spectral.Spectrum, 1)
+	a := &spectral.Analyzer{
+		Size:     size,
+		Hop:      size,
+		Window:   spectral.Hann,
+		Readings: readings,
+	}
+	assertEqual(t, "empty", a.Snapshot(), spectral.Spectrum{})
+	sink, err := a.Sink()(size, pipe.SignalProperties{Channels: 1, SampleRate: 8000})
+	assertEqual(t, "error", err, nil)
+	in := signal.Allocator{Channels: 1, Length: size, Capacity: size}.Float64()
+	for i := 0; i < size; i++ {
+		in.SetSample(i, 0.5)
+	}
+	assertEqual(t, "sink error", sink.SinkFunc(in), nil)
+	first := <-readings
+
+	// buffered and received readings are not changed.
+	for i := 0; i < size; i++ {
+		in.SetSample(i, 0.25)
+	}
+	allocs := testing.AllocsPerRun(10, func() {
+		sink.SinkFunc(in)
+	})
+	assertEqual(t, "allocs", allocs, 0.0)
+	second := <-readings
+	assertNear(t, "change", first.Magnitudes[0]-second.Magnitudes[0], 6.0206, 1e-3)
+	assertEqual(t, "snapshot", a.Snapshot(), second)
+}
+
+func TestErrors(t *testing.T) {
+	testError := func(processor pipe.ProcessorAllocatorFunc) func(*testing.T) {
+		return func(t *testing.T) {
+			t.Helper()
+			_, err := pipe.Routing{
+				Source:     noise(0).Source(),
+				Processors: pipe.Processors(processor),
+				Sink:       (&mock.Sink{}).Sink(),
+			}.Line(bufferSize)
+			assertEqual(t, "error", err != nil, true)
+		}
+	}
+	frame := func(int, []complex128) {}
+	t.Run("size", testError((&spectral.STFT{Size: 1000, Frame: frame}).Processor()))
+	t.Run("hop", testError((&spectral.STFT{Size: 1024, Hop: 2048, Frame: frame}).Processor()))
+	t.Run("window", testError((&spectral.STFT{Size: 1024, Window: 10, Frame: frame}).Processor()))
+	t.Run("frame", testError((&spectral.STFT{Size: 1024}).Processor()))
+}
+
+func assertNear(t *testing.T, name string, result, expected, delta float64) {
+	t.Helper()
+	if math.Abs(result-expected) > delta {
+		t.Fatalf("%v\nresult: \t%v\nexpected: \t%v", name, result, expected)
+	}
+}
+
+func assertEqual(t *testing.T, name string, result, expected interface{}) {
+	t.Helper()
+	if !reflect.DeepEqual(expected, result) {
+		t.Fatalf("%v\nresult: \t%T\t%+v \nexpected: \t%T\t%+v", name, result, result, expected, expected)
+	}
+}
@@ -0,0 +1,179 @@
+// Package spectral provides short-time Fourier transform framework for
+// spectral processing and analysis.
+//
+// Signal is split into overlapping frames of Size samples, which start
+// every Hop samples. Each frame is windowed and transformed into the
+// spectrum. STFT processor passes spectrum of each frame to the user's
+// callback and transforms it back with weighted overlap-add. Frames
+// don't depend on the buffer size of the pipe, input is buffered
+// internally and processor reports the latency of the framing.
+package spectral
+
+import (
+	"fmt"
+	"math/cmplx"
+
+	"pipelined.dev/signal"
+
+	"pipelined.dev/pipe"
+)
+
+// FrameFunc processes the spectrum of the frame in place. Spectrum has
+// Size/2+1 bins from DC to Nyquist frequency. Frames of all channels
+// are processed in order for each hop.
+type FrameFunc func(channel int, spectrum []complex128)
+
+// STFT is the processor that modifies the signal in frequency domain.
+// Size is the frame size and must be a power of two. Hop is the number
+// of samples between frames, Size/4 is used if it's zero. Window is
+// applied before the forward and after the inverse transform. Latency of
+// the processor is Size samples: the output of each frame is complete
+// only after its last sample is received.
+type STFT struct {
+	Size   int
+	Hop    int
+	Window Window
+	Frame  FrameFunc
+}
+
+// Processor returns allocator of the STFT processor.
+func (s *STFT) Processor() pipe.ProcessorAllocatorFunc {
+	return func(bufferSize int, props pipe.SignalProperties) (pipe.Processor, pipe.SignalProperties, error) {
+		if s.Frame == nil {
+			return pipe.Processor{}, pipe.SignalProperties{}, fmt.Errorf("stft frame function is not set")
+		}
+		f, err := newFramer(s.Size, s.Hop, s.Window, props.Channels)
+		if err != nil {
+			return pipe.Processor{}, pipe.SignalProperties{}, err
+		}
+		latency := f.size
+		// normalization of overlapped squared windows.
+		norm := make([]float64, f.hop)
+		for i := range norm {
+			var sum float64
+			for j := i; j < f.size; j += f.hop {
+				sum += f.window[j] * f.window[j]
+			}
+			if sum > 1e-9 {
+				norm[i] = 1 / sum
+			}
+		}
+		// overlap-add accumulators and output fifo of each channel.
+		acc := make([][]float64, props.Channels)
+		output := make([][]float64, props.Channels)
+		for c := range acc {
+			acc[c] = make([]float64, f.size)
+			output[c] = make([]float64, f.hop)
+		}
+		return pipe.Processor{
+			ProcessFunc: func(in, out signal.Floating) error {
+				for i := 0; i < in.Length(); i++ {
+					for c := range acc {
+						idx := in.BufferIndex(c, i)
+						f.input[c][f.pos] = in.Sample(idx)
+						out.SetSample(idx, output[c][f.pos-(f.size-f.hop)])
+					}
+					if !f.next() {
+						continue
+					}
+					for c := range acc {
+						spectrum := f.analyze(c)
+						s.Frame(c, spectrum)
+						f.synthesize(spectrum)
+						for j, v := range f.frame {
+							acc[c][j] += real(v) * f.window[j] * norm[j%f.hop]
+						}
+						copy(output[c], acc[c][:f.hop])
+						copy(acc[c], acc[c][f.hop:])
+						for j := f.size - f.hop; j < f.size; j++ {
+							acc[c][j] = 0
+						}
+					}
+					f.shift()
+				}
+				return nil
+			},
+			Latency: latency,
+			Tail:    latency,
+		}, props, nil
+	}
+}
+
+// framer splits the signal of all channels into windowed frames.
+type framer struct {
+	size   int
+	hop    int
+	window []float64
+	fft    *FFT
+	// input fifo of each channel and the position of the next sample.
+	input [][]float64
+	pos   int
+	frame []complex128
+}
+
+func newFramer(size, hop int, window Window, channels int) (*framer, error) {
+	fft, err := NewFFT(size)
+	if err != nil {
+		return nil, err
+	}
+	if hop == 0 {
+		hop = size / 4
+	}
+	if hop < 1 || hop > size {
+		return nil, fmt.Errorf("invalid hop %d for frame size %d", hop, size)
+	}
+	w, err := window.coefficients(size)
+	if err != nil {
+		return nil, err
+	}
+	input := make([][]float64, channels)
+	for c := range input {
+		input[c] = make([]float64, size)
+	}
+	return &framer{
+		size:   size,
+		hop:    hop,
+		window: w,
+		fft:    fft,
+		input:  input,
+		pos:    size - hop,
+		frame:  make([]complex128, size),
+	}, nil
+}
+
+// next advances the position and returns true if the frame is ready.
+func (f *framer) next() bool {
+	if f.pos++; f.pos < f.size {
+		return false
+	}
+	f.pos = f.size - f.hop
+	return true
+}
+
+// analyze returns the spectrum of the channel frame. Spectrum is valid
+// until the next call.
+func (f *framer) analyze(channel int) []complex128 {
+	for i, v := range f.input[channel] {
+		f.frame[i] = complex(v*f.window[i], 0)
+	}
+	f.fft.Transform(f.frame)
+	return f.frame[:f.size/2+1]
+}
+
+// synthesize restores the full spectrum of real signal from its first
+// half and transforms it back into the frame.
+func (f *framer) synthesize(spectrum []complex128) {
+	for k := 1; k < f.size/2; k++ {
+		f.frame[f.size-k] = cmplx.Conj(spectrum[k])
+	}
+	f.frame[0] = complex(real(f.frame[0]), 0)
+	f.frame[f.size/2] = complex(real(f.frame[f.size/2]), 0)
+	f.fft.Inverse(f.frame)
+}
+
+// shift drops the oldest hop of samples from input fifos.
+func (f *framer) shift() {
+	for c := range f.input {
+		copy(f.input[c], f.input[c][f.hop:])
+	}
+}
@@ -0,0 +1,51 @@
+package spectral
+
+import (
+	"fmt"
+
+	"pipelined.dev/pipe/internal/dsp"
+)
+
+// Window is the type of window function applied to frames.
+type Window uint8
+
+// Supported windows. Periodic versions are used, so windows sum to a
+// constant when frames overlap with common hop sizes.
+const (
+	Hann Window = iota
+	Hamming
+	Blackman
+)
+
+// String returns the name of the window.
+func (w Window) String() string {
+	switch w {
+	case Hann:
+		return "hann"
+	case Hamming:
+		return "hamming"
+	case Blackman:
+		return "blackman"
+	}
+	return fmt.Sprintf("window %d", int(w))
+}
+
+// coefficients returns the window of provided size.
+func (w Window) coefficients(size int) ([]float64, error) {
+	var a0, a1, a2 float64
+	switch w {
+	case Hann:
+		a0, a1 = 0.5, 0.5
+	case Hamming:
+		a0, a1 = 0.54, 0.46
+	case Blackman:
+		a0, a1, a2 = 0.42, 0.5, 0.08
+	default:
+		return nil, fmt.Errorf("unknown %v", w)
+	}
+	c := make([]float64, size)
+	for i := range c {
+		c[i] = dsp.Cosine(a0, a1, a2, float64(i)/float64(size))
+	}
+	return c, nil
+}
@@ -0,0 +1,392 @@
+// Package wav provides source and sink of WAV files. PCM with 8, 16, 24
+// and 32 bits depth and IEEE float with 32 and 64 bits depth are
+// supported, including WAVE_FORMAT_EXTENSIBLE files with channel masks.
+package wav
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"pipelined.dev/signal"
+
+	"pipelined.dev/pipe"
+	"pipelined.dev/pipe/mutability"
+	"pipelined.dev/pipe/pcm"
+)
+
+// ErrFormat is returned when the file is not a valid WAV file or has
+// unsupported format.
+var ErrFormat = errors.New("invalid wav format")
+
+// Format codes.
+const (
+	formatPCM        = 0x0001
+	formatFloat      = 0x0003
+	formatExtensible = 0xfffe
+)
+
+// unknownSize is the size of the data chunk in streamed files.
+const unknownSize = math.MaxUint32
+
+// Speaker positions of the channel mask.
+const (
+	speakerFrontLeft   = 0x1
+	speakerFrontRight  = 0x2
+	speakerFrontCenter = 0x4
+	speakerLFE         = 0x8
+	speakerBackLeft    = 0x10
+	speakerBackRight   = 0x20
+	speakerSideLeft    = 0x200
+	speakerSideRight   = 0x400
+)
+
+// channelMasks maps layouts to channel masks.
+var channelMasks = map[pipe.ChannelLayout]uint32{
+	pipe.MonoLayout:       speakerFrontCenter,
+	pipe.StereoLayout:     speakerFrontLeft | speakerFrontRight,
+	pipe.QuadLayout:       speakerFrontLeft | speakerFrontRight | speakerBackLeft | speakerBackRight,
+	pipe.Surround51Layout: speakerFrontLeft | speakerFrontRight | speakerFrontCenter | speakerLFE | speakerBackLeft | speakerBackRight,
+	pipe.Surround71Layout: speakerFrontLeft | speakerFrontRight | speakerFrontCenter | speakerLFE | speakerBackLeft | speakerBackRight | speakerSideLeft | speakerSideRight,
+}
+
+// subformatGUID is the tail of the extensible subformat GUID. The first
+// two bytes of GUID contain the format code.
+var subformatGUID = [14]byte{0x00, 0x00, 0x00, 0x00, 0x10, 0x00, 0x80, 0x00, 0x00, 0xaa, 0x00, 0x38, 0x9b, 0x71}
+
+// header is the format of WAV file.
+type header struct {
+	encoding    pcm.Encoding
+	channels    int
+	sampleRate  uint32
+	channelMask uint32
+	dataSize    uint32
+}
+
+// Source reads signal from WAV file. If Reader implements io.Seeker,
+// the source can be moved with Seek mutation.
+type Source struct {
+	Reader io.Reader
+
+	mutability mutability.Mutability
+	data       io.LimitedReader
+	dataStart  int64
+	dataSize   int64
+	frameSize  int
+}
+
+// id returns the mutability of the source.
+func (s *Source) id() mutability.Mutability {
+	if s.mutability == mutability.Immutable() {
+		s.mutability = mutability.Mutable()
+	}
+	return s.mutability
+}
+
+// Source returns allocator of the WAV source. Header is read during the
+// allocation.
+func (s *Source) Source() pipe.SourceAllocatorFunc {
+	return func(bufferSize int) (pipe.Source, pipe.SignalProperties, error) {
+		h, err := readHeader(s.Reader)
+		if err != nil {
+			return pipe.Source{}, pipe.SignalProperties{}, err
+		}
+		s.dataStart = -1
+		if seeker, ok := s.Reader.(io.Seeker); ok {
+			if s.dataStart, err = seeker.Seek(0, io.SeekCurrent); err != nil {
+				return pipe.Source{}, pipe.SignalProperties{}, err
+			}
+		}
+		s.frameSize = h.encoding.Size() * h.channels
+		s.dataSize = int64(h.dataSize)
+		if h.dataSize == unknownSize {
+			s.dataSize = math.MaxInt64
+		}
+		s.data = io.LimitedReader{R: s.Reader, N: s.dataSize}
+
+		source, props, err := (&pcm.Source{
+			Reader:     &s.data,
+			Encoding:   h.encoding,
+			Channels:   h.channels,
+			SampleRate: signal.Frequency(h.sampleRate),
+		}).Source()(bufferSize)
+		if err != nil {
+			return pipe.Source{}, pipe.SignalProperties{}, err
+		}
+		source.Mutability = s.id()
+		props.Layout = h.layout()
+		if h.dataSize != unknownSize {
+			props.Length = int(s.dataSize) / s.frameSize
+		}
+		return source, props, nil
+	}
+}
+
+// Seek returns mutation that moves the source to provided position in
+// samples per channel. The Reader must implement io.Seeker.
+func (s *Source) Seek(pos int) mutability.Mutation {
+	return s.id().Mutate(func() error {
+		seeker, ok := s.Reader.(io.Seeker)
+		if !ok {
+			return fmt.Errorf("wav source doesn't support seeking")
+		}
+		offset := int64(pos) * int64(s.frameSize)
+		if pos < 0 || offset > s.dataSize {
+			return fmt.Errorf("invalid seek position %d", pos)
+		}
+		if _, err := seeker.Seek(s.dataStart+offset, io.SeekStart); err != nil {
+			return err
+		}
+		s.data.N = s.dataSize - offset
+		return nil
+	})
+}
+
+// Sink writes signal into WAV file. Header is written during the
+// allocation and sizes are fixed up when the sink is flushed. Encoding
+// defines the sample format of the file, S16LE is used by default.
+// WAVE_FORMAT_EXTENSIBLE header is written for files with more than two
+// channels or more than 16 bits depth.
+type Sink struct {
+	Writer   io.WriteSeeker
+	Encoding pcm.Encoding
+}
+
+// Sink returns allocator of the WAV sink.
+func (s *Sink) Sink() pipe.SinkAllocatorFunc {
+	return func(bufferSize int, props pipe.SignalProperties) (pipe.Sink, error) {
+		h := header{
+			encoding:    s.Encoding,
+			channels:    props.Channels,
+			sampleRate:  uint32(props.SampleRate),
+			channelMask: channelMasks[props.Layout],
+		}
+		data := countWriter{w: s.Writer}
+		sink, err := (&pcm.Sink{
+			Writer:   &data,
+			Encoding: s.Encoding,
+		}).Sink()(bufferSize, props)
+		if err != nil {
+			return pipe.Sink{}, err
+		}
+		start, err := s.Writer.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return pipe.Sink{}, err
+		}
+		if _, err := s.Writer.Write(h.bytes()); err != nil {
+			return pipe.Sink{}, err
+		}
+		flush := sink.FlushFunc
+		sink.FlushFunc = func(ctx context.Context) error {
+			if err := flush(ctx); err != nil {
+				return err
+			}
+			return h.fix(s.Writer, start, data.n)
+		}
+		return sink, nil
+	}
+}
+
+// fix writes the header with provided data size. Pad byte is appended if
+// the size is odd.
+func (h header) fix(w io.WriteSeeker, start, size int64) error {
+	if size > unknownSize-1 {
+		return fmt.Errorf("wav data size %d exceeds the limit", size)
+	}
+	end := start + int64(len(h.bytes())) + size
+	if size%2 == 1 {
+		if _, err := w.Write([]byte{0}); err != nil {
+			return err
+		}
+		end++
+	}
+	h.dataSize = uint32(size)
+	if _, err := w.Seek(start, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := w.Write(h.bytes()); err != nil {
+		return err
+	}
+	_, err := w.Seek(end, io.SeekStart)
+	return err
+}
+
+// extensible returns true if header requires WAVE_FORMAT_EXTENSIBLE.
+func (h header) extensible() bool {
+	return h.channels > 2 || h.encoding.Size() > 2 && h.format() == formatPCM
+}
+
+// format returns the format code of the encoding.
+func (h header) format() uint16 {
+	if h.encoding == pcm.F32LE || h.encoding == pcm.F64LE {
+		return formatFloat
+	}
+	return formatPCM
+}
+
+// bytes returns the encoded header up to the data chunk content.
+func (h header) bytes() []byte {
+	var fmtChunk bytes.Buffer
+	size := h.encoding.Size()
+	format := h.format()
+	if h.extensible() {
+		format = formatExtensible
+	}
+	write(&fmtChunk,
+		format,
+		uint16(h.channels),
+		h.sampleRate,
+		h.sampleRate*uint32(size*h.channels),
+		uint16(size*h.channels),
+		uint16(size*8),
+	)
+	if h.extensible() {
+		write(&fmtChunk,
+			uint16(22),
+			uint16(size*8),
+			h.channelMask,
+			h.format(),
+			subformatGUID,
+		)
+	}
+
+	var b bytes.Buffer
+	riffSize := 4 + 8 + fmtChunk.Len() + 8 + int(h.dataSize) + int(h.dataSize%2)
+	b.WriteString("RIFF")
+	write(&b, uint32(riffSize))
+	b.WriteString("WAVE")
+	b.WriteString("fmt ")
+	write(&b, uint32(fmtChunk.Len()))
+	b.Write(fmtChunk.Bytes())
+	b.WriteString("data")
+	write(&b, h.dataSize)
+	return b.Bytes()
+}
+
+// write writes little-endian values into the buffer.
+func write(b *bytes.Buffer, values ...interface{}) {
+	for _, v := range values {
+		// writes to bytes.Buffer don't fail.
+		_ = binary.Write(b, binary.LittleEndian, v)
+	}
+}
+
+// readHeader reads chunks up to the data chunk content.
+func readHeader(r io.Reader) (header, error) {
+	var riff [12]byte
+	if _, err := io.ReadFull(r, riff[:]); err != nil {
+		return header{}, fmt.Errorf("%w: %v", ErrFormat, err)
+	}
+	if string(riff[0:4]) != "RIFF" || string(riff[8:12]) != "WAVE" {
+		return header{}, fmt.Errorf("%w: not a RIFF WAVE file", ErrFormat)
+	}
+
+	var (
+		h     header
+		fmtOK bool
+	)
+	for {
+		var chunk [8]byte
+		if _, err := io.ReadFull(r, chunk[:]); err != nil {
+			return header{}, fmt.Errorf("%w: data chunk not found: %v", ErrFormat, err)
+		}
+		id, size := string(chunk[0:4]), binary.LittleEndian.Uint32(chunk[4:8])
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return header{}, fmt.Errorf("%w: fmt chunk size %d", ErrFormat, size)
+			}
+			data := make([]byte, size+size%2)
+			if _, err := io.ReadFull(r, data); err != nil {
+				return header{}, fmt.Errorf("%w: %v", ErrFormat, err)
+			}
+			if err := h.parseFormat(data[:size]); err != nil {
+				return header{}, err
+			}
+			fmtOK = true
+		case "data":
+			if !fmtOK {
+				return header{}, fmt.Errorf("%w: data chunk before fmt chunk", ErrFormat)
+			}
+			h.dataSize = size
+			return h, nil
+		default:
+			if _, err := io.CopyN(io.Discard, r, int64(size+size%2)); err != nil {
+				return header{}, fmt.Errorf("%w: %v", ErrFormat, err)
+			}
+		}
+	}
+}
+
+// parseFormat parses the content of fmt chunk.
+func (h *header) parseFormat(data []byte) error {
+	format := binary.LittleEndian.Uint16(data[0:2])
+	h.channels = int(binary.LittleEndian.Uint16(data[2:4]))
+	h.sampleRate = binary.LittleEndian.Uint32(data[4:8])
+	blockAlign := int(binary.LittleEndian.Uint16(data[12:14]))
+	bitDepth := int(binary.LittleEndian.Uint16(data[14:16]))
+	if format == formatExtensible {
+		if len(data) < 40 {
+			return fmt.Errorf("%w: extensible fmt chunk size %d", ErrFormat, len(data))
+		}
+		h.channelMask = binary.LittleEndian.Uint32(data[20:24])
+		format = binary.LittleEndian.Uint16(data[24:26])
+	}
+
+	switch {
+	case format == formatPCM && bitDepth == 8:
+		h.encoding = pcm.U8
+	case format == formatPCM && bitDepth == 16:
+		h.encoding = pcm.S16LE
+	case format == formatPCM && bitDepth == 24:
+		h.encoding = pcm.S24LE
+	case format == formatPCM && bitDepth == 32:
+		h.encoding = pcm.S32LE
+	case format == formatFloat && bitDepth == 32:
+		h.encoding = pcm.F32LE
+	case format == formatFloat && bitDepth == 64:
+		h.encoding = pcm.F64LE
+	default:
+		return fmt.Errorf("%w: unsupported format 0x%04x with %d bits depth", ErrFormat, format, bitDepth)
+	}
+	if h.channels == 0 || blockAlign != h.channels*h.encoding.Size() {
+		return fmt.Errorf("%w: %d channels with block align %d", ErrFormat, h.channels, blockAlign)
+	}
+	return nil
+}
+
+// layout returns the channel layout of the header. Files without
+// channel mask are considered mono or stereo.
+func (h header) layout() pipe.ChannelLayout {
+	if h.channelMask == 0 {
+		switch h.channels {
+		case 1:
+			return pipe.MonoLayout
+		case 2:
+			return pipe.StereoLayout
+		}
+		return pipe.UnknownLayout
+	}
+	for layout, mask := range channelMasks {
+		if mask == h.channelMask && layout.Channels() == h.channels {
+			return layout
+		}
+	}
+	return pipe.UnknownLayout
+}
+
+// countWriter counts the bytes written into the underlying writer.
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,198 @@
+package wav_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"pipelined.dev/pipe"
+	"pipelined.dev/pipe/mock"
+	"pipelined.dev/pipe/pcm"
+	"pipelined.dev/pipe/wav"
+)
+
+const bufferSize = 512
+
+func TestRoundTrip(t *testing.T) {
+	testEncoding := func(encoding pcm.Encoding, channels, length int, layout pipe.ChannelLayout) func(*testing.T) {
+		return func(t *testing.T) {
+			t.Helper()
+			path := filepath.Join(t.TempDir(), "test.wav")
+			f, err := os.Create(path)
+			assertEqual(t, "create error", err, nil)
+			source := &mock.Source{
+				Limit:      length,
+				Channels:   channels,
+				SampleRate: 48000,
+				Value:      0.5,
+			}
+			run(t, withLayout(source.Source(), layout), (&wav.Sink{Writer: f, Encoding: encoding}).Sink())
+			assertEqual(t, "close error", f.Close(), nil)
+
+			info, err := os.Stat(path)
+			assertEqual(t, "stat error", err, nil)
+			assertEqual(t, "even size", info.Size()%2, int64(0))
+
+			f, err = os.Open(path)
+			assertEqual(t, "open error", err, nil)
+			defer f.Close()
+			sink := &mock.Sink{}
+			line := run(t, (&wav.Source{Reader: f}).Source(), sink.Sink())
+			props := line.Stages()[0].Properties
+			assertEqual(t, "sample rate", props.SampleRate, source.SampleRate)
+			assertEqual(t, "channels", props.Channels, channels)
+			assertEqual(t, "length", props.Length, length)
+			assertEqual(t, "layout", props.Layout, layout)
+			assertEqual(t, "samples", sink.Counter.Samples, length)
+			for i := 0; i < sink.Values.Len(); i++ {
+				if v := sink.Values.Sample(i); math.Abs(v-0.5) > 1e-2 {
+					t.Fatalf("value %v at index %d", v, i)
+				}
+			}
+		}
+	}
+	t.Run("u8", testEncoding(pcm.U8, 1, 1001, pipe.MonoLayout))
+	t.Run("s16le", testEncoding(pcm.S16LE, 2, 1000, pipe.StereoLayout))
+	t.Run("s24le", testEncoding(pcm.S24LE, 1, 999, pipe.MonoLayout))
+	t.Run("s32le", testEncoding(pcm.S32LE, 4, 1000, pipe.QuadLayout))
+	t.Run("f32le", testEncoding(pcm.F32LE, 6, 1000, pipe.Surround51Layout))
+	t.Run("f64le", testEncoding(pcm.F64LE, 8, 1000, pipe.Surround71Layout))
+	t.Run("unknown layout", testEncoding(pcm.S16LE, 3, 1000, pipe.UnknownLayout))
+}
+
+func TestSourceChunks(t *testing.T) {
+	var b bytes.Buffer
+	b.WriteString("RIFF")
+	write(&b, uint32(0))
+	b.WriteString("WAVE")
+	// odd-sized chunk is padded.
+	b.WriteString("LIST")
+	write(&b, uint32(3), []byte{1, 2, 3, 0})
+	b.WriteString("fmt ")
+	write(&b, uint32(16), uint16(1), uint16(1), uint32(8000), uint32(16000), uint16(2), uint16(16))
+	b.WriteString("data")
+	write(&b, uint32(4), int16(math.MinInt16), int16(0))
+
+	sink := &mock.Sink{}
+	line := run(t, (&wav.Source{Reader: &b}).Source(), sink.Sink())
+	assertEqual(t, "length", line.Stages()[0].Properties.Length, 2)
+	assertEqual(t, "samples", sink.Counter.Samples, 2)
+	assertEqual(t, "first", sink.Values.Sample(0), -1.0)
+	assertEqual(t, "second", sink.Values.Sample(1), 0.0)
+}
+
+func TestSeek(t *testing.T) {
+	var b bytes.Buffer
+	b.WriteString("RIFF")
+	write(&b, uint32(0))
+	b.WriteString("WAVE")
+	b.WriteString("fmt ")
+	write(&b, uint32(16), uint16(3), uint16(1), uint32(8000), uint32(64000), uint16(8), uint16(64))
+	b.WriteString("data")
+	write(&b, uint32(8*bufferSize*2))
+	for i := 0; i < bufferSize*2; i++ {
+		write(&b, float64(i))
+	}
+
+	source := &wav.Source{Reader: bytes.NewReader(b.Bytes())}
+	sink := &mock.Sink{}
+	line, err := pipe.Routing{
+		Source: source.Source(),
+		Sink:   sink.Sink(),
+	}.Line(bufferSize)
+	assertEqual(t, "line error", err, nil)
+	r := pipe.NewRenderer(context.Background(), line, source.Seek(bufferSize+10))
+	assertEqual(t, "render error", r.Next(), nil)
+	assertEqual(t, "samples", sink.Counter.Samples, bufferSize-10)
+	assertEqual(t, "first", sink.Values.Sample(0), float64(bufferSize+10))
+
+	r.Push(source.Seek(5))
+	assertEqual(t, "render error", r.Next(), nil)
+	assertEqual(t, "samples", sink.Counter.Samples, 2*bufferSize-10)
+	assertEqual(t, "seek", sink.Values.Sample(bufferSize-10), 5.0)
+
+	r.Push(source.Seek(2*bufferSize + 1))
+	err = r.Next()
+	assertEqual(t, "seek error", err != nil && !errors.Is(err, io.EOF), true)
+}
+
+func TestErrors(t *testing.T) {
+	testSource := func(data []byte, expected error) func(*testing.T) {
+		return func(t *testing.T) {
+			t.Helper()
+			_, err := pipe.Routing{
+				Source: (&wav.Source{Reader: bytes.NewReader(data)}).Source(),
+				Sink:   (&mock.Sink{}).Sink(),
+			}.Line(bufferSize)
+			assertEqual(t, "error", errors.Is(err, expected), true)
+		}
+	}
+	header := func(format, bitDepth uint16) []byte {
+		var b bytes.Buffer
+		b.WriteString("RIFF")
+		write(&b, uint32(0))
+		b.WriteString("WAVE")
+		b.WriteString("fmt ")
+		write(&b, uint32(16), format, uint16(1), uint32(8000), uint32(0), bitDepth/8, bitDepth)
+		b.WriteString("data")
+		write(&b, uint32(0))
+		return b.Bytes()
+	}
+	t.Run("empty", testSource(nil, wav.ErrFormat))
+	t.Run("not wav", testSource([]byte("RIFF\x00\x00\x00\x00AVI LIST"), wav.ErrFormat))
+	t.Run("no data", testSource(header(1, 16)[:36], wav.ErrFormat))
+	t.Run("unsupported bit depth", testSource(header(1, 12), wav.ErrFormat))
+	t.Run("unsupported format", testSource(header(2, 16), wav.ErrFormat))
+
+	t.Run("seek not supported", func(t *testing.T) {
+		source := &wav.Source{Reader: bytes.NewBuffer(header(1, 16))}
+		line, err := pipe.Routing{
+			Source: source.Source(),
+			Sink:   (&mock.Sink{}).Sink(),
+		}.Line(bufferSize)
+		assertEqual(t, "line error", err, nil)
+		err = pipe.NewRenderer(context.Background(), line, source.Seek(0)).Next()
+		assertEqual(t, "seek error", err != nil && !errors.Is(err, io.EOF), true)
+	})
+}
+
+// withLayout sets the channel layout of the source.
+func withLayout(fn pipe.SourceAllocatorFunc, layout pipe.ChannelLayout) pipe.SourceAllocatorFunc {
+	return func(bufferSize int) (pipe.Source, pipe.SignalProperties, error) {
+		source, props, err := fn(bufferSize)
+		props.Layout = layout
+		return source, props, err
+	}
+}
+
+func run(t *testing.T, source pipe.SourceAllocatorFunc, sink pipe.SinkAllocatorFunc) *pipe.Line {
+	t.Helper()
+	line, err := pipe.Routing{
+		Source: source,
+		Sink:   sink,
+	}.Line(bufferSize)
+	assertEqual(t, "line error", err, nil)
+	err = pipe.New(context.Background(), pipe.WithLines(line)).Wait()
+	assertEqual(t, "run error", err, nil)
+	return line
+}
+
+func write(b *bytes.Buffer, values ...interface{}) {
+	for _, v := range values {
+		_ = binary.Write(b, binary.LittleEndian, v)
+	}
+}
+
+func assertEqual(t *testing.T, name string, result, expected interface{}) {
+	t.Helper()
+	if !reflect.DeepEqual(expected, result) {
+		t.Fatalf("%v\nresult: \t%T\t%+v \nexpected: \t%T\t%+v", name, result, result, expected, expected)
+	}
+}